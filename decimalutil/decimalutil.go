@@ -61,3 +61,13 @@ func UsageRate(val, total decimal.Decimal) string {
 	buf.WriteString("%")
 	return buf.String()
 }
+
+// 将最小货币单位的整数金额转换为小数金额 例如 exp 为 2 时 1234 转换为 12.34
+func FromMinorUnits(amount int64, exp int32) decimal.Decimal {
+	return decimal.New(amount, -exp)
+}
+
+// 将小数金额转换为最小货币单位的整数金额 多余的小数位四舍五入
+func ToMinorUnits(amount decimal.Decimal, exp int32) int64 {
+	return amount.Shift(exp).Round(0).IntPart()
+}
@@ -7,7 +7,6 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/shopspring/decimal v1.4.0
 	github.com/stretchr/testify v1.12.0
-	github.com/technoweenie/multipartstreamer v1.0.1
 	go.uber.org/zap v1.28.0
 	golang.org/x/text v0.41.0
 	gopkg.in/yaml.v3 v3.0.1
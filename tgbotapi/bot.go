@@ -3,7 +3,7 @@
 package tgbotapi
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,14 +11,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/byepp/util/zaputil"
 
-	"github.com/technoweenie/multipartstreamer"
 	"go.uber.org/zap"
 )
 
@@ -33,8 +31,21 @@ type BotAPI struct {
 	Debug  bool   `json:"debug"`
 	Buffer int    `json:"buffer"`
 
+	// MaxRetries is how many times a request is repeated after Telegram
+	// asked to retry later, or moved the chat to a supergroup.
+	MaxRetries int `json:"max_retries"`
+
 	Self   User         `json:"-"`
 	Client *http.Client `json:"-"`
+
+	// Limiter throttles messages sent with Send, nil disables throttling.
+	Limiter *RateLimiter `json:"-"`
+	// OnChatMigrated is called when a request was redirected to the
+	// supergroup a group chat has been upgraded to.
+	OnChatMigrated func(fromChatID, toChatID int64) `json:"-"`
+
+	apiEndpoint  string
+	fileEndpoint string
 }
 
 // NewBotAPI creates a new BotAPI instance.
@@ -49,11 +60,16 @@ func NewBotAPI(token string) (*BotAPI, error) {
 //
 // It requires a token, provided by @BotFather on Telegram.
 func NewBotAPIWithClient(token string, client *http.Client) (*BotAPI, error) {
-	bot := &BotAPI{
-		Token:  token,
-		Client: client,
-		Buffer: 100,
-	}
+	return NewBotAPIWithAPIEndpoint(token, APIEndpoint, client)
+}
+
+// NewBotAPIWithAPIEndpoint creates a new BotAPI instance
+// and allows you to pass API endpoint and a http.Client.
+//
+// apiEndpoint is formatted with Sprintf like APIEndpoint, with the token
+// and the method name.
+func NewBotAPIWithAPIEndpoint(token, apiEndpoint string, client *http.Client) (*BotAPI, error) {
+	bot := newBotAPI(token, apiEndpoint, client)
 
 	self, err := bot.GetMe()
 	if err != nil {
@@ -65,11 +81,113 @@ func NewBotAPIWithClient(token string, client *http.Client) (*BotAPI, error) {
 	return bot, nil
 }
 
+// newBotAPI creates a BotAPI without loading Self.
+func newBotAPI(token, apiEndpoint string, client *http.Client) *BotAPI {
+	return &BotAPI{
+		Token:       token,
+		Client:      client,
+		Buffer:      100,
+		MaxRetries:  3,
+		Limiter:     NewRateLimiter(),
+		apiEndpoint: apiEndpoint,
+	}
+}
+
+// SetAPIEndpoint changes the endpoint requests are made to.
+func (bot *BotAPI) SetAPIEndpoint(apiEndpoint string) {
+	bot.apiEndpoint = apiEndpoint
+}
+
+// SetFileEndpoint changes the endpoint files are downloaded from. It is
+// formatted with Sprintf like FileEndpoint, with the token and file path.
+func (bot *BotAPI) SetFileEndpoint(fileEndpoint string) {
+	bot.fileEndpoint = fileEndpoint
+}
+
+// SetServerURL points the bot to a self-hosted Bot API server, such as
+// "http://localhost:8081", for both requests and file downloads.
+//
+// Call LogOut with the default endpoints before switching a bot to its
+// own server.
+func (bot *BotAPI) SetServerURL(serverURL string) {
+	serverURL = strings.TrimSuffix(serverURL, "/")
+
+	bot.apiEndpoint = serverURL + "/bot%s/%s"
+	bot.fileEndpoint = serverURL + "/file/bot%s/%s"
+}
+
+// methodURL returns the URL of an API method.
+func (bot *BotAPI) methodURL(endpoint string) string {
+	apiEndpoint := bot.apiEndpoint
+	if apiEndpoint == "" {
+		apiEndpoint = APIEndpoint
+	}
+
+	return fmt.Sprintf(apiEndpoint, bot.Token, endpoint)
+}
+
+// fileURL returns the URL to download a file from, or the file path
+// itself for files served by a Bot API server running in local mode.
+func (bot *BotAPI) fileURL(file File) string {
+	if file.IsLocal() {
+		return file.FilePath
+	}
+
+	fileEndpoint := bot.fileEndpoint
+	if fileEndpoint == "" {
+		fileEndpoint = FileEndpoint
+	}
+
+	return fmt.Sprintf(fileEndpoint, bot.Token, file.FilePath)
+}
+
 // MakeRequest makes a request to a specific endpoint with our token.
 func (bot *BotAPI) MakeRequest(endpoint string, params url.Values) (APIResponse, error) {
-	method := fmt.Sprintf(APIEndpoint, bot.Token, endpoint)
+	return bot.MakeRequestWithContext(context.Background(), endpoint, params)
+}
+
+// MakeRequestWithContext makes a request to a specific endpoint with our
+// token. The request is aborted when ctx is cancelled.
+//
+// Requests hitting flood control are repeated once the delay asked by
+// Telegram has passed, and requests to a group that was upgraded are
+// repeated with the new supergroup ID, up to MaxRetries times.
+func (bot *BotAPI) MakeRequestWithContext(ctx context.Context, endpoint string, params url.Values) (APIResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := bot.makeRequest(ctx, endpoint, params)
+		if err == nil || attempt >= bot.MaxRetries {
+			return resp, err
+		}
 
-	resp, err := bot.Client.PostForm(method, params)
+		chatID, retry := bot.prepareRetry(ctx, endpoint, err, params.Get("chat_id"))
+		if !retry {
+			return resp, err
+		}
+
+		if chatID != params.Get("chat_id") {
+			params = cloneValues(params)
+			params.Set("chat_id", chatID)
+		}
+	}
+}
+
+// makeRequest makes a single request to a specific endpoint.
+func (bot *BotAPI) makeRequest(ctx context.Context, endpoint string, params url.Values) (APIResponse, error) {
+	return bot.post(ctx, endpoint, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
+}
+
+// post makes a single request to a specific endpoint with a body of the
+// given content type.
+func (bot *BotAPI) post(ctx context.Context, endpoint, contentType string, body io.Reader) (APIResponse, error) {
+	method := bot.methodURL(endpoint)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", method, body)
+	if err != nil {
+		return APIResponse{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := bot.Client.Do(req)
 	if err != nil {
 		return APIResponse{}, err
 	}
@@ -86,7 +204,7 @@ func (bot *BotAPI) MakeRequest(endpoint string, params url.Values) (APIResponse,
 	}
 
 	if !apiResp.Ok {
-		return apiResp, errors.New(apiResp.Description)
+		return apiResp, newError(apiResp)
 	}
 
 	return apiResp, nil
@@ -116,19 +234,64 @@ func (bot *BotAPI) decodeAPIResponse(responseBody io.Reader, resp *APIResponse)
 	return data, nil
 }
 
-// makeMessageRequest makes a request to a method that returns a Message.
-func (bot *BotAPI) makeMessageRequest(endpoint string, params url.Values) (Message, error) {
-	resp, err := bot.MakeRequest(endpoint, params)
-	if err != nil {
-		return Message{}, err
+// prepareRetry decides if a failed request should be repeated, waiting
+// for the flood control delay if Telegram asked for one. It returns the
+// chat ID to repeat the request with.
+func (bot *BotAPI) prepareRetry(ctx context.Context, endpoint string, err error, chatID string) (string, bool) {
+	apiErr, ok := asError(err)
+	if !ok {
+		return chatID, false
 	}
 
-	var message Message
-	json.Unmarshal(resp.Result, &message)
+	if apiErr.MigrateToChatID != 0 && chatID != "" {
+		newChatID := strconv.FormatInt(apiErr.MigrateToChatID, 10)
 
-	bot.debugLog(endpoint, params, message)
+		log.Info("chat migrated to supergroup", zap.String("endpoint", endpoint),
+			zap.String("chat_id", chatID), zap.String("new_chat_id", newChatID))
 
-	return message, nil
+		if bot.OnChatMigrated != nil {
+			fromChatID, _ := strconv.ParseInt(chatID, 10, 64)
+			bot.OnChatMigrated(fromChatID, apiErr.MigrateToChatID)
+		}
+
+		return newChatID, true
+	}
+
+	if delay := apiErr.RetryAfterDuration(); delay > 0 {
+		log.Info("flood control exceeded, waiting", zap.String("endpoint", endpoint),
+			zap.String("chat_id", chatID), zap.Duration("retry_after", delay))
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return chatID, false
+		case <-timer.C:
+			return chatID, true
+		}
+	}
+
+	return chatID, false
+}
+
+// waitLimiter blocks until the Limiter allows a message to the chat.
+func (bot *BotAPI) waitLimiter(ctx context.Context, chatID string) error {
+	if bot.Limiter == nil {
+		return nil
+	}
+
+	return bot.Limiter.Wait(ctx, chatID)
+}
+
+// cloneValues returns a copy of v that can be modified safely.
+func cloneValues(v url.Values) url.Values {
+	c := make(url.Values, len(v))
+	for key, values := range v {
+		c[key] = append([]string(nil), values...)
+	}
+
+	return c
 }
 
 // UploadFile makes a request to the API with a file.
@@ -140,36 +303,39 @@ func (bot *BotAPI) makeMessageRequest(endpoint string, params url.Values) (Messa
 // Note that if your FileReader has a size set to -1, it will read
 // the file into memory to calculate a size.
 func (bot *BotAPI) UploadFile(endpoint string, params map[string]string, fieldname string, file interface{}) (APIResponse, error) {
-	ms := multipartstreamer.New()
+	return bot.UploadFilesWithContext(context.Background(), endpoint, params, []RequestFile{{Name: fieldname, File: file}})
+}
 
-	switch f := file.(type) {
-	case string:
-		ms.WriteFields(params)
+// UploadFileWithContext makes a request to the API with a file, like
+// UploadFile. The upload is aborted when ctx is cancelled.
+func (bot *BotAPI) UploadFileWithContext(ctx context.Context, endpoint string, params map[string]string, fieldname string, file interface{}) (APIResponse, error) {
+	return bot.UploadFilesWithContext(ctx, endpoint, params, []RequestFile{{Name: fieldname, File: file}})
+}
 
-		fileHandle, err := os.Open(f)
-		if err != nil {
-			return APIResponse{}, err
-		}
-		defer fileHandle.Close()
+// UploadFiles makes a request to the API with several files in a single
+// multipart request. Files are handled like in UploadFile.
+func (bot *BotAPI) UploadFiles(endpoint string, params map[string]string, files []RequestFile) (APIResponse, error) {
+	return bot.UploadFilesWithContext(context.Background(), endpoint, params, files)
+}
 
-		fi, err := os.Stat(f)
-		if err != nil {
-			return APIResponse{}, err
+// UploadFilesWithContext makes a request to the API with several files,
+// like UploadFiles. The upload is aborted when ctx is cancelled.
+//
+// Failed uploads are repeated like in MakeRequestWithContext, except for
+// requests with a FileReader of a known size, which can only be read once.
+func (bot *BotAPI) UploadFilesWithContext(ctx context.Context, endpoint string, params map[string]string, files []RequestFile) (APIResponse, error) {
+	retries := bot.MaxRetries
+
+	files = append([]RequestFile(nil), files...)
+	for i, file := range files {
+		f, ok := file.File.(FileReader)
+		if !ok {
+			continue
 		}
 
-		ms.WriteReader(fieldname, fileHandle.Name(), fi.Size(), fileHandle)
-	case FileBytes:
-		ms.WriteFields(params)
-
-		buf := bytes.NewBuffer(f.Bytes)
-		ms.WriteReader(fieldname, f.Name, int64(len(f.Bytes)), buf)
-	case FileReader:
-		ms.WriteFields(params)
-
 		if f.Size != -1 {
-			ms.WriteReader(fieldname, f.Name, f.Size, f.Reader)
-
-			break
+			retries = 0
+			continue
 		}
 
 		data, err := ioutil.ReadAll(f.Reader)
@@ -177,25 +343,47 @@ func (bot *BotAPI) UploadFile(endpoint string, params map[string]string, fieldna
 			return APIResponse{}, err
 		}
 
-		buf := bytes.NewBuffer(data)
+		files[i].File = FileBytes{Name: f.Name, Bytes: data}
+	}
 
-		ms.WriteReader(fieldname, f.Name, int64(len(data)), buf)
-	case url.URL:
-		params[fieldname] = f.String()
+	for attempt := 0; ; attempt++ {
+		resp, err := bot.uploadFiles(ctx, endpoint, params, files)
+		if err == nil || attempt >= retries {
+			return resp, err
+		}
 
-		ms.WriteFields(params)
-	default:
-		return APIResponse{}, errors.New(ErrBadFileType)
+		chatID, retry := bot.prepareRetry(ctx, endpoint, err, params["chat_id"])
+		if !retry {
+			return resp, err
+		}
+
+		// Copy params rather than changing the map of the caller.
+		retryParams := make(map[string]string, len(params))
+		for k, v := range params {
+			retryParams[k] = v
+		}
+		retryParams["chat_id"] = chatID
+		params = retryParams
 	}
+}
+
+// uploadFiles makes a single request to the API with files.
+func (bot *BotAPI) uploadFiles(ctx context.Context, endpoint string, params map[string]string, files []RequestFile) (APIResponse, error) {
+	body, err := newMultipartBody(params, files)
+	if err != nil {
+		return APIResponse{}, err
+	}
+	defer body.Close()
 
-	method := fmt.Sprintf(APIEndpoint, bot.Token, endpoint)
+	method := bot.methodURL(endpoint)
 
-	req, err := http.NewRequest("POST", method, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", method, body.Reader())
 	if err != nil {
 		return APIResponse{}, err
 	}
 
-	ms.SetupRequest(req)
+	req.Header.Set("Content-Type", body.contentType)
+	req.ContentLength = body.length
 
 	res, err := bot.Client.Do(req)
 	if err != nil {
@@ -208,9 +396,9 @@ func (bot *BotAPI) UploadFile(endpoint string, params map[string]string, fieldna
 		return APIResponse{}, err
 	}
 
-	//if bot.Debug {
-	//	log.Info("UploadFile", string(bytes))
-	//}
+	if bot.Debug {
+		log.Info("UploadFiles", zap.String("endpoint", endpoint), zap.ByteString("resp", bytes))
+	}
 
 	var apiResp APIResponse
 
@@ -220,7 +408,7 @@ func (bot *BotAPI) UploadFile(endpoint string, params map[string]string, fieldna
 	}
 
 	if !apiResp.Ok {
-		return APIResponse{}, errors.New(apiResp.Description)
+		return apiResp, newError(apiResp)
 	}
 
 	return apiResp, nil
@@ -229,6 +417,9 @@ func (bot *BotAPI) UploadFile(endpoint string, params map[string]string, fieldna
 // GetFileDirectURL returns direct URL to file
 //
 // It requires the FileID.
+//
+// A Bot API server running in local mode returns files as absolute paths
+// on its file system, which are returned as is.
 func (bot *BotAPI) GetFileDirectURL(fileID string) (string, error) {
 	file, err := bot.GetFile(FileConfig{fileID})
 
@@ -236,7 +427,7 @@ func (bot *BotAPI) GetFileDirectURL(fileID string) (string, error) {
 		return "", err
 	}
 
-	return file.Link(bot.Token), nil
+	return bot.fileURL(file), nil
 }
 
 // GetMe fetches the currently authenticated bot.
@@ -245,17 +436,12 @@ func (bot *BotAPI) GetFileDirectURL(fileID string) (string, error) {
 // and so you may get this data from BotAPI.Self without the need for
 // another request.
 func (bot *BotAPI) GetMe() (User, error) {
-	resp, err := bot.MakeRequest("getMe", nil)
-	if err != nil {
-		return User{}, err
-	}
-
-	var user User
-	json.Unmarshal(resp.Result, &user)
-
-	bot.debugLog("getMe", nil, user)
+	return bot.GetMeWithContext(context.Background())
+}
 
-	return user, nil
+// GetMeWithContext is GetMe with a context.
+func (bot *BotAPI) GetMeWithContext(ctx context.Context) (User, error) {
+	return DoWithContext(ctx, bot, methodRequest[User]("getMe"))
 }
 
 // IsMessageToMe returns true if message directed to this bot.
@@ -269,86 +455,57 @@ func (bot *BotAPI) IsMessageToMe(message Message) bool {
 //
 // It requires the Chattable to send.
 func (bot *BotAPI) Send(c Chattable) (Message, error) {
-	switch c.(type) {
-	case Fileable:
-		return bot.sendFile(c.(Fileable))
-	default:
-		return bot.sendChattable(c)
-	}
+	return bot.SendWithContext(context.Background(), c)
 }
 
-// debugLog checks if the bot is currently running in debug mode, and if
-// so will display information about the request and response in the
-// debug log.
-func (bot *BotAPI) debugLog(context string, v url.Values, message interface{}) {
-	if bot.Debug {
-		log.Info("debug", zap.String("context", context), zap.Any("req", v))
-		log.Info("debug", zap.String("context", context), zap.Any("resp", message))
-	}
-}
-
-// sendExisting will send a Message with an existing file to Telegram.
-func (bot *BotAPI) sendExisting(method string, config Fileable) (Message, error) {
-	v, err := config.values()
-
-	if err != nil {
-		return Message{}, err
-	}
-
-	message, err := bot.makeMessageRequest(method, v)
-	if err != nil {
-		return Message{}, err
+// SendWithContext will send a Chattable item to Telegram, aborting the
+// request when ctx is cancelled.
+//
+// It requires the Chattable to send. Media groups result in several
+// messages, send them with SendMediaGroup instead.
+func (bot *BotAPI) SendWithContext(ctx context.Context, c Chattable) (Message, error) {
+	switch c.(type) {
+	case MediaGroupConfig, *MediaGroupConfig:
+		return Message{}, errors.New(ErrMediaGroupSend)
 	}
 
-	return message, nil
-}
-
-// uploadAndSend will send a Message with a new file to Telegram.
-func (bot *BotAPI) uploadAndSend(method string, config Fileable) (Message, error) {
-	params, err := config.params()
+	resp, err := bot.request(ctx, c, true)
 	if err != nil {
 		return Message{}, err
 	}
 
-	file := config.getFile()
-
-	resp, err := bot.UploadFile(method, params, config.name(), file)
-	if err != nil {
+	var message Message
+	if err := decodeResult(resp.Result, &message); err != nil {
 		return Message{}, err
 	}
 
-	var message Message
-	json.Unmarshal(resp.Result, &message)
-
-	bot.debugLog(method, nil, message)
+	bot.debugLog(c.method(), nil, message)
 
 	return message, nil
 }
 
-// sendFile determines if the file is using an existing file or uploading
-// a new file, then sends it as needed.
-func (bot *BotAPI) sendFile(config Fileable) (Message, error) {
-	if config.useExistingFile() {
-		return bot.sendExisting(config.method(), config)
-	}
-
-	return bot.uploadAndSend(config.method(), config)
+// SendMediaGroup sends a group of photos, videos, documents or audio files
+// as an album, uploading the local files in a single request.
+//
+// It returns the messages that were sent, one for each media item.
+func (bot *BotAPI) SendMediaGroup(config MediaGroupConfig) ([]Message, error) {
+	return bot.SendMediaGroupWithContext(context.Background(), config)
 }
 
-// sendChattable sends a Chattable.
-func (bot *BotAPI) sendChattable(config Chattable) (Message, error) {
-	v, err := config.values()
-	if err != nil {
-		return Message{}, err
-	}
-
-	message, err := bot.makeMessageRequest(config.method(), v)
+// SendMediaGroupWithContext sends a media group like SendMediaGroup,
+// aborting the request when ctx is cancelled.
+func (bot *BotAPI) SendMediaGroupWithContext(ctx context.Context, config MediaGroupConfig) ([]Message, error) {
+	return DoWithContext(ctx, bot, config)
+}
 
-	if err != nil {
-		return Message{}, err
+// debugLog checks if the bot is currently running in debug mode, and if
+// so will display information about the request and response in the
+// debug log.
+func (bot *BotAPI) debugLog(context string, v url.Values, message interface{}) {
+	if bot.Debug {
+		log.Info("debug", zap.String("context", context), zap.Any("req", v))
+		log.Info("debug", zap.String("context", context), zap.Any("resp", message))
 	}
-
-	return message, nil
 }
 
 // GetUserProfilePhotos gets a user's profile photos.
@@ -356,46 +513,19 @@ func (bot *BotAPI) sendChattable(config Chattable) (Message, error) {
 // It requires UserID.
 // Offset and Limit are optional.
 func (bot *BotAPI) GetUserProfilePhotos(config UserProfilePhotosConfig) (UserProfilePhotos, error) {
-	v := url.Values{}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-	if config.Offset != 0 {
-		v.Add("offset", strconv.Itoa(config.Offset))
-	}
-	if config.Limit != 0 {
-		v.Add("limit", strconv.Itoa(config.Limit))
-	}
-
-	resp, err := bot.MakeRequest("getUserProfilePhotos", v)
-	if err != nil {
-		return UserProfilePhotos{}, err
-	}
-
-	var profilePhotos UserProfilePhotos
-	json.Unmarshal(resp.Result, &profilePhotos)
-
-	bot.debugLog("GetUserProfilePhoto", v, profilePhotos)
-
-	return profilePhotos, nil
+	return Do(bot, config)
 }
 
 // GetFile returns a File which can download a file from Telegram.
 //
 // Requires FileID.
 func (bot *BotAPI) GetFile(config FileConfig) (File, error) {
-	v := url.Values{}
-	v.Add("file_id", config.FileID)
-
-	resp, err := bot.MakeRequest("getFile", v)
-	if err != nil {
-		return File{}, err
-	}
-
-	var file File
-	json.Unmarshal(resp.Result, &file)
-
-	bot.debugLog("GetFile", v, file)
+	return bot.GetFileWithContext(context.Background(), config)
+}
 
-	return file, nil
+// GetFileWithContext is GetFile with a context.
+func (bot *BotAPI) GetFileWithContext(ctx context.Context, config FileConfig) (File, error) {
+	return DoWithContext(ctx, bot, config)
 }
 
 // GetUpdates fetches updates.
@@ -406,33 +536,32 @@ func (bot *BotAPI) GetFile(config FileConfig) (File, error) {
 // Set Timeout to a large number to reduce requests so you can get updates
 // instantly instead of having to wait between requests.
 func (bot *BotAPI) GetUpdates(config UpdateConfig) ([]Update, error) {
-	v := url.Values{}
-	if config.Offset != 0 {
-		v.Add("offset", strconv.Itoa(config.Offset))
-	}
-	if config.Limit > 0 {
-		v.Add("limit", strconv.Itoa(config.Limit))
-	}
-	if config.Timeout > 0 {
-		v.Add("timeout", strconv.Itoa(config.Timeout))
-	}
-
-	resp, err := bot.MakeRequest("getUpdates", v)
-	if err != nil {
-		return []Update{}, err
-	}
+	return bot.GetUpdatesWithContext(context.Background(), config)
+}
 
-	var updates []Update
-	json.Unmarshal(resp.Result, &updates)
+// GetUpdatesWithContext fetches updates like GetUpdates. A pending long
+// poll is aborted when ctx is cancelled.
+func (bot *BotAPI) GetUpdatesWithContext(ctx context.Context, config UpdateConfig) ([]Update, error) {
+	return DoWithContext(ctx, bot, config)
+}
 
-	bot.debugLog("getUpdates", v, updates)
+// LogOut logs the bot out from the cloud Bot API server, which must be
+// done before running it with a self-hosted server. The bot cannot log in
+// again to the cloud server for 10 minutes.
+func (bot *BotAPI) LogOut() (APIResponse, error) {
+	return bot.request(context.Background(), methodRequest[bool]("logOut"), false)
+}
 
-	return updates, nil
+// Close closes the bot instance before moving it from one local server to
+// another. The webhook should be removed first, and the method cannot be
+// called again for 10 minutes after the bot was launched.
+func (bot *BotAPI) Close() (APIResponse, error) {
+	return bot.request(context.Background(), methodRequest[bool]("close"), false)
 }
 
 // RemoveWebhook unsets the webhook.
 func (bot *BotAPI) RemoveWebhook() (APIResponse, error) {
-	return bot.MakeRequest("setWebhook", url.Values{})
+	return bot.request(context.Background(), methodRequest[bool]("setWebhook"), false)
 }
 
 // SetWebhook sets a webhook.
@@ -442,187 +571,80 @@ func (bot *BotAPI) RemoveWebhook() (APIResponse, error) {
 // If you do not have a legitimate TLS certificate, you need to include
 // your self signed certificate with the config.
 func (bot *BotAPI) SetWebhook(config WebhookConfig) (APIResponse, error) {
+	return bot.SetWebhookWithContext(context.Background(), config)
+}
 
-	if config.Certificate == nil {
-		v := url.Values{}
-		v.Add("url", config.URL.String())
-		if config.MaxConnections != 0 {
-			v.Add("max_connections", strconv.Itoa(config.MaxConnections))
-		}
-
-		return bot.MakeRequest("setWebhook", v)
-	}
-
-	params := make(map[string]string)
-	params["url"] = config.URL.String()
-	if config.MaxConnections != 0 {
-		params["max_connections"] = strconv.Itoa(config.MaxConnections)
-	}
-
-	resp, err := bot.UploadFile("setWebhook", params, "certificate", config.Certificate)
-	if err != nil {
-		return APIResponse{}, err
-	}
-
-	return resp, nil
+// SetWebhookWithContext is SetWebhook with a context.
+func (bot *BotAPI) SetWebhookWithContext(ctx context.Context, config WebhookConfig) (APIResponse, error) {
+	return bot.request(ctx, config, false)
 }
 
 // GetWebhookInfo allows you to fetch information about a webhook and if
 // one currently is set, along with pending update count and error messages.
 func (bot *BotAPI) GetWebhookInfo() (WebhookInfo, error) {
-	resp, err := bot.MakeRequest("getWebhookInfo", url.Values{})
-	if err != nil {
-		return WebhookInfo{}, err
-	}
-
-	var info WebhookInfo
-	err = json.Unmarshal(resp.Result, &info)
-
-	return info, err
+	return Do(bot, methodRequest[WebhookInfo]("getWebhookInfo"))
 }
 
 // GetUpdatesChan starts and returns a channel for getting updates.
+//
+// The channel is never closed, use StartPolling to be able to stop
+// receiving updates, or StartPollingWithStore to resume after a restart.
 func (bot *BotAPI) GetUpdatesChan(config UpdateConfig) (UpdatesChannel, error) {
-	ch := make(chan Update, bot.Buffer)
-
-	go func() {
-		for {
-			updates, err := bot.GetUpdates(config)
-			if err != nil {
-				log.Error("bot.GetUpdates failed", zap.Error(err))
-				log.Info("Failed to get updates, retrying in 3 seconds...")
-				time.Sleep(time.Second * 3)
-				continue
-			}
-
-			for _, update := range updates {
-				if update.UpdateID >= config.Offset {
-					config.Offset = update.UpdateID + 1
-					ch <- update
-				}
-			}
-		}
-	}()
-
-	return ch, nil
+	return bot.StartPolling(context.Background(), config).Updates(), nil
 }
 
 // ListenForWebhook registers a http handler for a webhook.
+//
+// The handler is registered on http.DefaultServeMux, use NewWebhookServer
+// to serve updates on another mux or to check the secret token.
 func (bot *BotAPI) ListenForWebhook(pattern string) UpdatesChannel {
-	ch := make(chan Update, bot.Buffer)
+	server := NewWebhookServer(bot, WebhookConfig{})
 
-	http.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-		bytes, _ := ioutil.ReadAll(r.Body)
+	http.Handle(pattern, server)
 
-		var update Update
-		json.Unmarshal(bytes, &update)
-
-		ch <- update
-	})
-
-	return ch
+	return server.Updates()
 }
 
 // AnswerInlineQuery sends a response to an inline query.
 //
 // Note that you must respond to an inline query within 30 seconds.
 func (bot *BotAPI) AnswerInlineQuery(config InlineConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	v.Add("inline_query_id", config.InlineQueryID)
-	v.Add("cache_time", strconv.Itoa(config.CacheTime))
-	v.Add("is_personal", strconv.FormatBool(config.IsPersonal))
-	v.Add("next_offset", config.NextOffset)
-	data, err := json.Marshal(config.Results)
-	if err != nil {
-		return APIResponse{}, err
-	}
-	v.Add("results", string(data))
-	v.Add("switch_pm_text", config.SwitchPMText)
-	v.Add("switch_pm_parameter", config.SwitchPMParameter)
-
-	bot.debugLog("answerInlineQuery", v, nil)
-
-	return bot.MakeRequest("answerInlineQuery", v)
+	return bot.request(context.Background(), config, false)
 }
 
 // AnswerCallbackQuery sends a response to an inline query callback.
 func (bot *BotAPI) AnswerCallbackQuery(config CallbackConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	v.Add("callback_query_id", config.CallbackQueryID)
-	if config.Text != "" {
-		v.Add("text", config.Text)
-	}
-	v.Add("show_alert", strconv.FormatBool(config.ShowAlert))
-	if config.URL != "" {
-		v.Add("url", config.URL)
-	}
-	v.Add("cache_time", strconv.Itoa(config.CacheTime))
-
-	bot.debugLog("answerCallbackQuery", v, nil)
-
-	return bot.MakeRequest("answerCallbackQuery", v)
+	return bot.request(context.Background(), config, false)
 }
 
 // KickChatMember kicks a user from a chat. Note that this only will work
 // in supergroups, and requires the bot to be an admin. Also note they
 // will be unable to rejoin until they are unbanned.
 func (bot *BotAPI) KickChatMember(config KickChatMemberConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-
-	if config.UntilDate != 0 {
-		v.Add("until_date", strconv.FormatInt(config.UntilDate, 10))
-	}
+	return bot.request(context.Background(), config, false)
+}
 
-	bot.debugLog("kickChatMember", v, nil)
+// BanChatMember bans a user from a chat. The bot must be an administrator
+// with the can_restrict_members right.
+func (bot *BotAPI) BanChatMember(config BanChatMemberConfig) (bool, error) {
+	return Do(bot, config)
+}
 
-	return bot.MakeRequest("kickChatMember", v)
+// SetChatPermissions sets the default permissions of the members of a
+// chat. The bot must be an administrator with the can_restrict_members
+// right.
+func (bot *BotAPI) SetChatPermissions(config SetChatPermissionsConfig) (bool, error) {
+	return Do(bot, config)
 }
 
 // LeaveChat makes the bot leave the chat.
 func (bot *BotAPI) LeaveChat(config ChatConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-
-	bot.debugLog("leaveChat", v, nil)
-
-	return bot.MakeRequest("leaveChat", v)
+	return bot.request(context.Background(), chatRequest[bool]{config, "leaveChat"}, false)
 }
 
 // GetChat gets information about a chat.
 func (bot *BotAPI) GetChat(config ChatConfig) (Chat, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-
-	resp, err := bot.MakeRequest("getChat", v)
-	if err != nil {
-		return Chat{}, err
-	}
-
-	var chat Chat
-	err = json.Unmarshal(resp.Result, &chat)
-
-	bot.debugLog("getChat", v, chat)
-
-	return chat, err
+	return Do(bot, chatRequest[Chat]{config, "getChat"})
 }
 
 // GetChatAdministrators gets a list of administrators in the chat.
@@ -630,91 +652,28 @@ func (bot *BotAPI) GetChat(config ChatConfig) (Chat, error) {
 // If none have been appointed, only the creator will be returned.
 // Bots are not shown, even if they are an administrator.
 func (bot *BotAPI) GetChatAdministrators(config ChatConfig) ([]ChatMember, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-
-	resp, err := bot.MakeRequest("getChatAdministrators", v)
-	if err != nil {
-		return []ChatMember{}, err
-	}
-
-	var members []ChatMember
-	err = json.Unmarshal(resp.Result, &members)
-
-	bot.debugLog("getChatAdministrators", v, members)
-
-	return members, err
+	return Do(bot, chatRequest[[]ChatMember]{config, "getChatAdministrators"})
 }
 
 // GetChatMembersCount gets the number of users in a chat.
 func (bot *BotAPI) GetChatMembersCount(config ChatConfig) (int, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-
-	resp, err := bot.MakeRequest("getChatMembersCount", v)
+	count, err := Do(bot, chatRequest[int]{config, "getChatMembersCount"})
 	if err != nil {
 		return -1, err
 	}
 
-	var count int
-	err = json.Unmarshal(resp.Result, &count)
-
-	bot.debugLog("getChatMembersCount", v, count)
-
-	return count, err
+	return count, nil
 }
 
 // GetChatMember gets a specific chat member.
 func (bot *BotAPI) GetChatMember(config ChatConfigWithUser) (ChatMember, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-
-	resp, err := bot.MakeRequest("getChatMember", v)
-	if err != nil {
-		return ChatMember{}, err
-	}
-
-	var member ChatMember
-	err = json.Unmarshal(resp.Result, &member)
-
-	bot.debugLog("getChatMember", v, member)
-
-	return member, err
+	return Do(bot, config)
 }
 
 // UnbanChatMember unbans a user from a chat. Note that this only will work
 // in supergroups and channels, and requires the bot to be an admin.
 func (bot *BotAPI) UnbanChatMember(config ChatMemberConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername != "" {
-		v.Add("chat_id", config.SuperGroupUsername)
-	} else if config.ChannelUsername != "" {
-		v.Add("chat_id", config.ChannelUsername)
-	} else {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-
-	bot.debugLog("unbanChatMember", v, nil)
-
-	return bot.MakeRequest("unbanChatMember", v)
+	return bot.request(context.Background(), chatMemberRequest{config, "unbanChatMember"}, false)
 }
 
 // RestrictChatMember to restrict a user in a supergroup. The bot must be an
@@ -722,185 +681,144 @@ func (bot *BotAPI) UnbanChatMember(config ChatMemberConfig) (APIResponse, error)
 //appropriate admin rights. Pass True for all boolean parameters to lift
 //restrictions from a user. Returns True on success.
 func (bot *BotAPI) RestrictChatMember(config RestrictChatMemberConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername != "" {
-		v.Add("chat_id", config.SuperGroupUsername)
-	} else if config.ChannelUsername != "" {
-		v.Add("chat_id", config.ChannelUsername)
-	} else {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-
-	if &config.CanSendMessages != nil {
-		v.Add("can_send_messages", strconv.FormatBool(*config.CanSendMessages))
-	}
-	if &config.CanSendMediaMessages != nil {
-		v.Add("can_send_media_messages", strconv.FormatBool(*config.CanSendMediaMessages))
-	}
-	if &config.CanSendOtherMessages != nil {
-		v.Add("can_send_other_messages", strconv.FormatBool(*config.CanSendOtherMessages))
-	}
-	if &config.CanAddWebPagePreviews != nil {
-		v.Add("can_add_web_page_previews", strconv.FormatBool(*config.CanAddWebPagePreviews))
-	}
-	if config.UntilDate != 0 {
-		v.Add("until_date", strconv.FormatInt(config.UntilDate, 10))
-	}
-
-	bot.debugLog("restrictChatMember", v, nil)
-
-	return bot.MakeRequest("restrictChatMember", v)
+	return bot.request(context.Background(), config, false)
 }
 
 // PromoteChatMember add admin rights to user
 func (bot *BotAPI) PromoteChatMember(config PromoteChatMemberConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	if config.SuperGroupUsername != "" {
-		v.Add("chat_id", config.SuperGroupUsername)
-	} else if config.ChannelUsername != "" {
-		v.Add("chat_id", config.ChannelUsername)
-	} else {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	}
-	v.Add("user_id", strconv.Itoa(config.UserID))
-
-	if &config.CanChangeInfo != nil {
-		v.Add("can_change_info", strconv.FormatBool(*config.CanChangeInfo))
-	}
-	if &config.CanPostMessages != nil {
-		v.Add("can_post_messages", strconv.FormatBool(*config.CanPostMessages))
-	}
-	if &config.CanEditMessages != nil {
-		v.Add("can_edit_messages", strconv.FormatBool(*config.CanEditMessages))
-	}
-	if &config.CanDeleteMessages != nil {
-		v.Add("can_delete_messages", strconv.FormatBool(*config.CanDeleteMessages))
-	}
-	if &config.CanInviteUsers != nil {
-		v.Add("can_invite_users", strconv.FormatBool(*config.CanInviteUsers))
-	}
-	if &config.CanRestrictMembers != nil {
-		v.Add("can_restrict_members", strconv.FormatBool(*config.CanRestrictMembers))
-	}
-	if &config.CanPinMessages != nil {
-		v.Add("can_pin_messages", strconv.FormatBool(*config.CanPinMessages))
-	}
-	if &config.CanPromoteMembers != nil {
-		v.Add("can_promote_members", strconv.FormatBool(*config.CanPromoteMembers))
-	}
-
-	bot.debugLog("promoteChatMember", v, nil)
-
-	return bot.MakeRequest("promoteChatMember", v)
+	return bot.request(context.Background(), config, false)
 }
 
 // GetGameHighScores allows you to get the high scores for a game.
 func (bot *BotAPI) GetGameHighScores(config GetGameHighScoresConfig) ([]GameHighScore, error) {
-	v, _ := config.values()
-
-	resp, err := bot.MakeRequest(config.method(), v)
-	if err != nil {
-		return []GameHighScore{}, err
-	}
-
-	var highScores []GameHighScore
-	err = json.Unmarshal(resp.Result, &highScores)
-
-	return highScores, err
+	return Do(bot, config)
 }
 
 // AnswerShippingQuery allows you to reply to Update with shipping_query parameter.
 func (bot *BotAPI) AnswerShippingQuery(config ShippingConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	v.Add("shipping_query_id", config.ShippingQueryID)
-	v.Add("ok", strconv.FormatBool(config.OK))
-	if config.OK == true {
-		data, err := json.Marshal(config.ShippingOptions)
-		if err != nil {
-			return APIResponse{}, err
-		}
-		v.Add("shipping_options", string(data))
-	} else {
-		v.Add("error_message", config.ErrorMessage)
-	}
-
-	bot.debugLog("answerShippingQuery", v, nil)
-
-	return bot.MakeRequest("answerShippingQuery", v)
+	return bot.request(context.Background(), config, false)
 }
 
 // AnswerPreCheckoutQuery allows you to reply to Update with pre_checkout_query.
 func (bot *BotAPI) AnswerPreCheckoutQuery(config PreCheckoutConfig) (APIResponse, error) {
-	v := url.Values{}
-
-	v.Add("pre_checkout_query_id", config.PreCheckoutQueryID)
-	v.Add("ok", strconv.FormatBool(config.OK))
-	if config.OK != true {
-		v.Add("error", config.ErrorMessage)
-	}
+	return bot.request(context.Background(), config, false)
+}
 
-	bot.debugLog("answerPreCheckoutQuery", v, nil)
+// CreateInvoiceLink creates a link to an invoice.
+func (bot *BotAPI) CreateInvoiceLink(config CreateInvoiceLinkConfig) (string, error) {
+	return Do(bot, config)
+}
 
-	return bot.MakeRequest("answerPreCheckoutQuery", v)
+// RefundStarPayment refunds a successful payment in Telegram Stars.
+func (bot *BotAPI) RefundStarPayment(config RefundStarPaymentConfig) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
 }
 
 // DeleteMessage deletes a message in a chat
 func (bot *BotAPI) DeleteMessage(config DeleteMessageConfig) (APIResponse, error) {
-	v, err := config.values()
-	if err != nil {
-		return APIResponse{}, err
-	}
-
-	bot.debugLog(config.method(), v, nil)
+	return bot.request(context.Background(), config, false)
+}
 
-	return bot.MakeRequest(config.method(), v)
+// DeleteMessages deletes several messages in a chat at once.
+func (bot *BotAPI) DeleteMessages(config DeleteMessagesConfig) (bool, error) {
+	return Do(bot, config)
 }
 
 // GetInviteLink get InviteLink for a chat
 func (bot *BotAPI) GetInviteLink(config ChatConfig) (string, error) {
-	v := url.Values{}
+	return Do(bot, chatRequest[string]{config, "exportChatInviteLink"})
+}
 
-	if config.SuperGroupUsername == "" {
-		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
-	} else {
-		v.Add("chat_id", config.SuperGroupUsername)
-	}
+// PinChatMessage pin message in supergroup
+func (bot *BotAPI) PinChatMessage(config PinChatMessageConfig) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
+}
 
-	resp, err := bot.MakeRequest("exportChatInviteLink", v)
-	if err != nil {
-		return "", err
-	}
+// UnpinChatMessage unpin message in supergroup
+func (bot *BotAPI) UnpinChatMessage(config UnpinChatMessageConfig) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
+}
 
-	var inviteLink string
-	err = json.Unmarshal(resp.Result, &inviteLink)
+// EditMessageMedia replaces the media of a message, uploading the new
+// file if needed. Edits of inline messages return an empty Message.
+func (bot *BotAPI) EditMessageMedia(config EditMessageMediaConfig) (Message, error) {
+	return Do(bot, config)
+}
 
-	return inviteLink, err
+// EditMessageLiveLocation moves a live location. Edits of inline messages
+// return an empty Message.
+func (bot *BotAPI) EditMessageLiveLocation(config EditMessageLiveLocationConfig) (Message, error) {
+	return Do(bot, config)
 }
 
-// PinChatMessage pin message in supergroup
-func (bot *BotAPI) PinChatMessage(config PinChatMessageConfig) (APIResponse, error) {
-	v, err := config.values()
-	if err != nil {
-		return APIResponse{}, err
-	}
+// StopMessageLiveLocation stops updating a live location before its live
+// period expires.
+func (bot *BotAPI) StopMessageLiveLocation(config StopMessageLiveLocationConfig) (Message, error) {
+	return Do(bot, config)
+}
 
-	bot.debugLog(config.method(), v, nil)
+// StopPoll stops a poll sent by the bot, and returns the final results.
+func (bot *BotAPI) StopPoll(config StopPollConfig) (Poll, error) {
+	return Do(bot, config)
+}
 
-	return bot.MakeRequest(config.method(), v)
+// ApproveChatJoinRequest approves a request of the user to join the chat.
+//
+// The bot must be an administrator with the can_invite_users right.
+func (bot *BotAPI) ApproveChatJoinRequest(config ChatMemberConfig) (APIResponse, error) {
+	return bot.request(context.Background(), chatMemberRequest{config, "approveChatJoinRequest"}, false)
 }
 
-// UnpinChatMessage unpin message in supergroup
-func (bot *BotAPI) UnpinChatMessage(config UnpinChatMessageConfig) (APIResponse, error) {
-	v, err := config.values()
-	if err != nil {
-		return APIResponse{}, err
-	}
+// DeclineChatJoinRequest declines a request of the user to join the chat.
+//
+// The bot must be an administrator with the can_invite_users right.
+func (bot *BotAPI) DeclineChatJoinRequest(config ChatMemberConfig) (APIResponse, error) {
+	return bot.request(context.Background(), chatMemberRequest{config, "declineChatJoinRequest"}, false)
+}
+
+// CreateForumTopic creates a topic in a forum supergroup.
+//
+// The bot must be an administrator with the can_manage_topics right.
+func (bot *BotAPI) CreateForumTopic(config CreateForumTopicConfig) (ForumTopic, error) {
+	return Do(bot, config)
+}
+
+// EditForumTopic changes the name or icon of a topic.
+func (bot *BotAPI) EditForumTopic(config EditForumTopicConfig) (APIResponse, error) {
+	return bot.forumTopicRequest(config)
+}
+
+// CloseForumTopic closes a topic, so that only administrators can post.
+func (bot *BotAPI) CloseForumTopic(config CloseForumTopicConfig) (APIResponse, error) {
+	return bot.forumTopicRequest(config)
+}
+
+// ReopenForumTopic reopens a closed topic.
+func (bot *BotAPI) ReopenForumTopic(config ReopenForumTopicConfig) (APIResponse, error) {
+	return bot.forumTopicRequest(config)
+}
+
+// DeleteForumTopic deletes a topic along with all its messages.
+func (bot *BotAPI) DeleteForumTopic(config DeleteForumTopicConfig) (APIResponse, error) {
+	return bot.forumTopicRequest(config)
+}
 
-	bot.debugLog(config.method(), v, nil)
+// forumTopicRequest makes a request acting on a forum topic.
+func (bot *BotAPI) forumTopicRequest(config Chattable) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
+}
+
+// SetMyCommands sets the command list shown in the command menu.
+func (bot *BotAPI) SetMyCommands(config SetMyCommandsConfig) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
+}
+
+// GetMyCommands gets the command list of a scope and language.
+func (bot *BotAPI) GetMyCommands(config GetMyCommandsConfig) ([]BotCommand, error) {
+	return Do(bot, config)
+}
 
-	return bot.MakeRequest(config.method(), v)
+// DeleteMyCommands deletes the command list of a scope and language.
+func (bot *BotAPI) DeleteMyCommands(config DeleteMyCommandsConfig) (APIResponse, error) {
+	return bot.request(context.Background(), config, false)
 }
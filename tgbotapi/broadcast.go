@@ -0,0 +1,333 @@
+package tgbotapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/byepp/util/fileutil"
+	"github.com/byepp/util/jsonutil"
+	"go.uber.org/zap"
+)
+
+// Constant values for the status of a BroadcastResult
+const (
+	BroadcastSent        = "sent"
+	BroadcastBlocked     = "blocked"
+	BroadcastDeactivated = "deactivated"
+	BroadcastError       = "error"
+)
+
+// DefaultBroadcastConcurrency is the number of messages a Broadcast sends
+// at the same time.
+const DefaultBroadcastConcurrency = 8
+
+// broadcastCheckpointEvery is the number of results after which the
+// checkpoint file is saved.
+const broadcastCheckpointEvery = 100
+
+// BroadcastResult is the outcome of sending a broadcast to one chat.
+type BroadcastResult struct {
+	ChatID    int64  `json:"chat_id"`
+	Status    string `json:"status"`
+	MessageID int    `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BroadcastProgress counts the outcomes of a Broadcast so far.
+type BroadcastProgress struct {
+	Total       int
+	Done        int
+	Sent        int
+	Blocked     int
+	Deactivated int
+	Failed      int
+}
+
+// broadcastCheckpoint is the content of a checkpoint file.
+type broadcastCheckpoint struct {
+	Results []BroadcastResult `json:"results"`
+}
+
+// Broadcast sends the same message to many chats.
+//
+// Messages are sent by Concurrency workers through the bot, so they are
+// paced by its Limiter and retried on flood control like any other. If
+// CheckpointPath is set, the outcome of every chat is saved there, and a
+// Broadcast started with the same file skips the chats already done.
+// Chats that failed with an unexpected error are tried again.
+type Broadcast struct {
+	Concurrency    int
+	CheckpointPath string
+	// OnProgress, if set, is called after every chat. Calls don't overlap.
+	OnProgress func(progress BroadcastProgress)
+
+	bot      *BotAPI
+	chatIDs  []int64
+	template Chattable
+
+	mu       sync.Mutex
+	results  map[int64]BroadcastResult
+	progress BroadcastProgress
+	unsaved  int
+}
+
+// NewBroadcast creates a Broadcast of template to chatIDs. The chat of
+// template is replaced with each of chatIDs. Duplicate chats get a single
+// message.
+//
+// Files should be sent by file ID, so that they are not uploaded for every
+// chat.
+func NewBroadcast(bot *BotAPI, template Chattable, chatIDs []int64) *Broadcast {
+	seen := make(map[int64]bool, len(chatIDs))
+	unique := make([]int64, 0, len(chatIDs))
+	for _, id := range chatIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return &Broadcast{
+		Concurrency: DefaultBroadcastConcurrency,
+		bot:         bot,
+		chatIDs:     unique,
+		template:    template,
+		results:     make(map[int64]BroadcastResult),
+	}
+}
+
+// Run sends the broadcast until every chat is done or ctx is cancelled,
+// and returns the final progress. The checkpoint is saved before it
+// returns, so that a cancelled Broadcast can be resumed.
+func (b *Broadcast) Run(ctx context.Context) (BroadcastProgress, error) {
+	if err := b.load(); err != nil {
+		return BroadcastProgress{}, err
+	}
+
+	pending := make(chan int64)
+	go func() {
+		defer close(pending)
+		for _, id := range b.chatIDs {
+			if b.done(id) {
+				continue
+			}
+			select {
+			case pending <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range pending {
+				b.send(ctx, id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.save(); err != nil {
+		return b.progress, err
+	}
+
+	return b.progress, ctx.Err()
+}
+
+// Results returns the outcome of every chat done so far, in the order of
+// the chat list.
+func (b *Broadcast) Results() []BroadcastResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	results := make([]BroadcastResult, 0, len(b.results))
+	for _, id := range b.chatIDs {
+		if result, ok := b.results[id]; ok {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// send sends the message to a chat and records the outcome.
+func (b *Broadcast) send(ctx context.Context, chatID int64) {
+	message, err := b.bot.SendWithContext(ctx, WithChatID(b.template, chatID))
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	result := BroadcastResult{ChatID: chatID, Status: BroadcastSent, MessageID: message.MessageID}
+	switch {
+	case err == nil:
+	case IsUserDeactivated(err):
+		result.Status = BroadcastDeactivated
+	case IsForbidden(err):
+		result.Status = BroadcastBlocked
+	default:
+		result.Status = BroadcastError
+		result.Error = err.Error()
+	}
+
+	b.record(result)
+}
+
+// record stores a result, updates the progress and saves the checkpoint
+// from time to time.
+func (b *Broadcast) record(result BroadcastResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.results[result.ChatID] = result
+	b.count(result)
+
+	b.unsaved++
+	if b.unsaved >= broadcastCheckpointEvery {
+		if err := b.save(); err != nil {
+			log.Error("save broadcast checkpoint", zap.String("path", b.CheckpointPath), zap.Error(err))
+		}
+	}
+
+	if b.OnProgress != nil {
+		b.OnProgress(b.progress)
+	}
+}
+
+// count adds a result to the progress.
+func (b *Broadcast) count(result BroadcastResult) {
+	b.progress.Done++
+	switch result.Status {
+	case BroadcastSent:
+		b.progress.Sent++
+	case BroadcastBlocked:
+		b.progress.Blocked++
+	case BroadcastDeactivated:
+		b.progress.Deactivated++
+	default:
+		b.progress.Failed++
+	}
+}
+
+// done returns if a chat has a final outcome.
+func (b *Broadcast) done(chatID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result, ok := b.results[chatID]
+	return ok && result.Status != BroadcastError
+}
+
+// load reads the checkpoint file, if any, and resets the progress to it.
+// Failed chats are forgotten, so that they are tried again.
+func (b *Broadcast) load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.CheckpointPath != "" && fileutil.IsFileExist(b.CheckpointPath) {
+		var checkpoint broadcastCheckpoint
+		if err := jsonutil.LoadFile(b.CheckpointPath, &checkpoint); err != nil {
+			return err
+		}
+		for _, result := range checkpoint.Results {
+			b.results[result.ChatID] = result
+		}
+	}
+
+	b.progress = BroadcastProgress{Total: len(b.chatIDs)}
+	for _, id := range b.chatIDs {
+		result, ok := b.results[id]
+		if !ok {
+			continue
+		}
+		if result.Status == BroadcastError {
+			delete(b.results, id)
+			continue
+		}
+		b.count(result)
+	}
+
+	return nil
+}
+
+// save writes all results to the checkpoint file. The caller must hold mu.
+func (b *Broadcast) save() error {
+	b.unsaved = 0
+	if b.CheckpointPath == "" {
+		return nil
+	}
+
+	checkpoint := broadcastCheckpoint{Results: make([]BroadcastResult, 0, len(b.results))}
+	for _, id := range b.chatIDs {
+		if result, ok := b.results[id]; ok {
+			checkpoint.Results = append(checkpoint.Results, result)
+		}
+	}
+
+	return jsonutil.SaveFile(b.CheckpointPath, checkpoint)
+}
+
+// WithChatID returns c sending to chatID instead of its own chat.
+func WithChatID(c Chattable, chatID int64) Chattable {
+	id := strconv.FormatInt(chatID, 10)
+	if f, ok := c.(Fileable); ok {
+		return fileableWithChatID{Fileable: f, chatID: id}
+	}
+
+	return chattableWithChatID{Chattable: c, chatID: id}
+}
+
+// chattableWithChatID overrides the chat of a Chattable.
+type chattableWithChatID struct {
+	Chattable
+	chatID string
+}
+
+func (c chattableWithChatID) values() (url.Values, error) {
+	v, err := c.Chattable.values()
+	if err != nil {
+		return v, err
+	}
+	v.Set("chat_id", c.chatID)
+
+	return v, nil
+}
+
+// fileableWithChatID overrides the chat of a Fileable.
+type fileableWithChatID struct {
+	Fileable
+	chatID string
+}
+
+func (c fileableWithChatID) values() (url.Values, error) {
+	v, err := c.Fileable.values()
+	if err != nil {
+		return v, err
+	}
+	v.Set("chat_id", c.chatID)
+
+	return v, nil
+}
+
+func (c fileableWithChatID) params() (map[string]string, error) {
+	params, err := c.Fileable.params()
+	if err != nil {
+		return params, err
+	}
+	params["chat_id"] = c.chatID
+
+	return params, nil
+}
@@ -0,0 +1,178 @@
+package tgbotapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxCallbackDataLength is the maximum length of callback data in bytes.
+const MaxCallbackDataLength = 64
+
+// callbackSeparator separates the prefix, fields and signature of encoded
+// callback data.
+const callbackSeparator = ":"
+
+// callbackSignatureLength is the number of HMAC bytes kept in a signature.
+// It is encoded to 8 characters.
+const callbackSignatureLength = 6
+
+// CallbackCodec encodes typed fields into callback data and back.
+//
+// Encoded data has the form "prefix:field1:field2". Integers are stored in
+// base 36 to save space. A signed codec appends a truncated HMAC of the
+// data, so that users can't forge callbacks with made-up fields.
+type CallbackCodec struct {
+	Prefix string
+
+	key []byte
+}
+
+// NewCallbackCodec creates a CallbackCodec for data starting with prefix.
+func NewCallbackCodec(prefix string) *CallbackCodec {
+	return &CallbackCodec{
+		Prefix: prefix,
+	}
+}
+
+// NewSignedCallbackCodec creates a CallbackCodec signing its data with key.
+func NewSignedCallbackCodec(prefix string, key []byte) *CallbackCodec {
+	return &CallbackCodec{
+		Prefix: prefix,
+		key:    key,
+	}
+}
+
+// Encode returns the callback data for fields, which may be strings, bools
+// or integers. Strings must not contain the ":" separator.
+func (c *CallbackCodec) Encode(fields ...interface{}) (string, error) {
+	parts := make([]string, 0, len(fields)+2)
+	parts = append(parts, c.Prefix)
+
+	for _, field := range fields {
+		var s string
+		switch value := field.(type) {
+		case string:
+			if strings.Contains(value, callbackSeparator) {
+				return "", fmt.Errorf("%s: field %q contains %q", ErrBadCallbackData, value, callbackSeparator)
+			}
+			s = value
+		case bool:
+			s = "0"
+			if value {
+				s = "1"
+			}
+		case int:
+			s = strconv.FormatInt(int64(value), 36)
+		case int64:
+			s = strconv.FormatInt(value, 36)
+		default:
+			return "", fmt.Errorf("%s: unsupported field type %T", ErrBadCallbackData, field)
+		}
+		parts = append(parts, s)
+	}
+
+	data := strings.Join(parts, callbackSeparator)
+	if c.key != nil {
+		data += callbackSeparator + c.sign(data)
+	}
+
+	if len(data) > MaxCallbackDataLength {
+		return "", errors.New(ErrCallbackDataTooLong)
+	}
+
+	return data, nil
+}
+
+// Button returns an inline keyboard button carrying the encoded fields.
+func (c *CallbackCodec) Button(text string, fields ...interface{}) (InlineKeyboardButton, error) {
+	data, err := c.Encode(fields...)
+	if err != nil {
+		return InlineKeyboardButton{}, err
+	}
+
+	return NewInlineKeyboardButtonData(text, data), nil
+}
+
+// Decode parses callback data produced by Encode, checking its prefix and
+// signature.
+func (c *CallbackCodec) Decode(data string) (CallbackData, error) {
+	if !c.Is(data) {
+		return CallbackData{}, errors.New(ErrBadCallbackData)
+	}
+
+	if c.key != nil {
+		i := strings.LastIndex(data, callbackSeparator)
+		if i < len(c.Prefix) || !hmac.Equal([]byte(data[i+1:]), []byte(c.sign(data[:i]))) {
+			return CallbackData{}, fmt.Errorf("%s: bad signature", ErrBadCallbackData)
+		}
+		data = data[:i]
+	}
+
+	var fields []string
+	if rest := data[len(c.Prefix):]; rest != "" {
+		fields = strings.Split(rest[len(callbackSeparator):], callbackSeparator)
+	}
+
+	return CallbackData{Fields: fields}, nil
+}
+
+// Is returns if data was encoded with the codec's prefix.
+func (c *CallbackCodec) Is(data string) bool {
+	return data == c.Prefix || strings.HasPrefix(data, c.Prefix+callbackSeparator)
+}
+
+// Matcher returns a Matcher accepting callback queries encoded with the
+// codec's prefix.
+func (c *CallbackCodec) Matcher() Matcher {
+	return func(update Update) bool {
+		return update.CallbackQuery != nil && c.Is(update.CallbackQuery.Data)
+	}
+}
+
+// sign returns the encoded truncated HMAC of data.
+func (c *CallbackCodec) sign(data string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(data))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureLength])
+}
+
+// CallbackData holds the decoded fields of callback data.
+type CallbackData struct {
+	Fields []string
+}
+
+// Len returns the number of fields.
+func (d CallbackData) Len() int {
+	return len(d.Fields)
+}
+
+// Field returns field i, or an empty string if there is none.
+func (d CallbackData) Field(i int) string {
+	if i < 0 || i >= len(d.Fields) {
+		return ""
+	}
+
+	return d.Fields[i]
+}
+
+// Int returns field i as an integer.
+func (d CallbackData) Int(i int) (int, error) {
+	n, err := d.Int64(i)
+	return int(n), err
+}
+
+// Int64 returns field i as a 64-bit integer.
+func (d CallbackData) Int64(i int) (int64, error) {
+	return strconv.ParseInt(d.Field(i), 36, 64)
+}
+
+// Bool returns field i as a bool.
+func (d CallbackData) Bool(i int) bool {
+	return d.Field(i) == "1"
+}
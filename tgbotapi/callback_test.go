@@ -0,0 +1,81 @@
+package tgbotapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackCodec(t *testing.T) {
+	codec := NewCallbackCodec("item")
+
+	data, err := codec.Encode("buy", 1000, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "item:buy:rs:1", data)
+
+	decoded, err := codec.Decode(data)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "buy", decoded.Field(0))
+	n, err := decoded.Int(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, n)
+	assert.True(t, decoded.Bool(2))
+
+	_, err = codec.Decode("other:buy")
+	assert.NotNil(t, err)
+	_, err = codec.Encode("a:b")
+	assert.NotNil(t, err)
+	_, err = codec.Encode(strings.Repeat("x", MaxCallbackDataLength))
+	assert.EqualError(t, err, ErrCallbackDataTooLong)
+}
+
+func TestSignedCallbackCodec(t *testing.T) {
+	codec := NewSignedCallbackCodec("page", []byte("secret"))
+
+	data, err := codec.Encode(3)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	decoded, err := codec.Decode(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3"}, decoded.Fields)
+
+	forged := strings.Replace(data, "page:3:", "page:4:", 1)
+	_, err = codec.Decode(forged)
+	assert.NotNil(t, err)
+	_, err = codec.Decode("page")
+	assert.NotNil(t, err)
+}
+
+func TestPaginatorMarkup(t *testing.T) {
+	var items []InlineKeyboardButton
+	for i := 0; i < 7; i++ {
+		items = append(items, NewInlineKeyboardButtonData(string(rune('a'+i)), "x"))
+	}
+	p := NewPaginator(NewCallbackCodec("p"), func() []InlineKeyboardButton {
+		return items
+	})
+	p.PageSize = 3
+
+	assert.Equal(t, 3, p.Pages())
+
+	markup, err := p.Markup(1)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, markup.InlineKeyboard, 4)
+	nav := markup.InlineKeyboard[3]
+	if assert.Len(t, nav, 3) {
+		assert.Equal(t, "p:0", *nav[0].CallbackData)
+		assert.Equal(t, "2/3", nav[1].Text)
+		assert.Equal(t, "p:2", *nav[2].CallbackData)
+	}
+
+	markup, err = p.Markup(5)
+	assert.Nil(t, err)
+	assert.Equal(t, "g", markup.InlineKeyboard[0][0].Text)
+}
@@ -0,0 +1,223 @@
+package tgbotapi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCaptchaTimeout is how long new members have to solve a Captcha.
+const DefaultCaptchaTimeout = 2 * time.Minute
+
+// DefaultCaptchaFailBan is how long new members failing a Captcha are
+// banned. Telegram bans for at least 30 seconds.
+const DefaultCaptchaFailBan = time.Minute
+
+// Captcha mutes new members of groups until they press a button, to keep
+// out spam bots.
+//
+// The bot must be an administrator allowed to restrict, ban and delete.
+// Members who don't press the button within Timeout are banned for
+// FailBan, which lets them join again once it expires.
+type Captcha struct {
+	Timeout time.Duration
+	// Text is the prompt, formatted with the first name of the member.
+	Text       string
+	ButtonText string
+	FailBan    time.Duration
+
+	codec *CallbackCodec
+
+	mu      sync.Mutex
+	pending map[chatUserKey]*captchaChallenge
+}
+
+// captchaChallenge is a prompt waiting for a member to press its button.
+type captchaChallenge struct {
+	messageID int
+	timer     *time.Timer
+}
+
+// NewCaptcha creates a Captcha. codec must not be shared with other
+// callbacks.
+func NewCaptcha(codec *CallbackCodec) *Captcha {
+	return &Captcha{
+		Timeout:    DefaultCaptchaTimeout,
+		Text:       "Welcome, %s! Press the button below to show you are not a robot.",
+		ButtonText: "I am not a robot",
+		FailBan:    DefaultCaptchaFailBan,
+		codec:      codec,
+		pending:    make(map[chatUserKey]*captchaChallenge),
+	}
+}
+
+// Challenge mutes a member and sends them the prompt. Members already
+// challenged are skipped.
+func (c *Captcha) Challenge(bot *BotAPI, chatID int64, user User) error {
+	key := chatUserKey{chatID: chatID, userID: user.ID}
+
+	c.mu.Lock()
+	if _, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		return nil
+	}
+	challenge := &captchaChallenge{}
+	c.pending[key] = challenge
+	c.mu.Unlock()
+
+	message, err := c.prompt(bot, chatID, user)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	challenge.messageID = message.MessageID
+	challenge.timer = time.AfterFunc(c.Timeout, func() {
+		c.fail(bot, key)
+	})
+
+	return nil
+}
+
+// HandleJoin challenges the members joining a chat, from a new chat
+// members message or a chat member update. Bots are not challenged.
+func (c *Captcha) HandleJoin(bot *BotAPI, update Update) {
+	var chatID int64
+	var users []User
+
+	switch {
+	case update.Message != nil && update.Message.NewChatMembers != nil:
+		chatID = update.Message.Chat.ID
+		users = *update.Message.NewChatMembers
+	case update.ChatMember != nil && update.ChatMember.Joined() && update.ChatMember.NewChatMember.User != nil:
+		chatID = update.ChatMember.Chat.ID
+		users = []User{*update.ChatMember.NewChatMember.User}
+	}
+
+	for _, user := range users {
+		if user.IsBot {
+			continue
+		}
+
+		if err := c.Challenge(bot, chatID, user); err != nil {
+			log.Error("captcha challenge", zap.Int64("chat_id", chatID), zap.Int("user_id", user.ID), zap.Error(err))
+		}
+	}
+}
+
+// HandleCallback lifts the restrictions of a member pressing the button of
+// their prompt, and answers the callback query.
+func (c *Captcha) HandleCallback(bot *BotAPI, update Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message == nil || query.From == nil {
+		return
+	}
+
+	text := ""
+	data, err := c.codec.Decode(query.Data)
+	if err == nil {
+		var userID int
+		userID, err = data.Int(0)
+		switch {
+		case err != nil:
+		case userID != query.From.ID:
+			text = "This button is not for you."
+		default:
+			err = c.solve(bot, query.Message, userID)
+		}
+	}
+	if err != nil {
+		log.Error("captcha", zap.String("data", query.Data), zap.Error(err))
+	}
+
+	if _, err := bot.AnswerCallbackQuery(NewCallback(query.ID, text)); err != nil {
+		log.Error("answer callback query", zap.String("id", query.ID), zap.Error(err))
+	}
+}
+
+// Register adds routes challenging new members and handling the buttons
+// of the prompts.
+func (c *Captcha) Register(r *Router) {
+	r.Handle(c.codec.Matcher(), c.HandleCallback)
+	r.ContentType(ContentTypeNewChatMembers, c.HandleJoin)
+	r.Handle(func(update Update) bool {
+		return update.ChatMember != nil && update.ChatMember.Joined()
+	}, c.HandleJoin)
+}
+
+// prompt mutes a member and sends them the prompt.
+func (c *Captcha) prompt(bot *BotAPI, chatID int64, user User) (Message, error) {
+	member := ChatMemberConfig{ChatID: chatID, UserID: user.ID}
+	if _, err := bot.MuteChatMember(member, 0); err != nil {
+		return Message{}, err
+	}
+
+	button, err := c.codec.Button(c.ButtonText, user.ID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	config := NewMessage(chatID, fmt.Sprintf(c.Text, user.FirstName))
+	config.ReplyMarkup = NewInlineKeyboardMarkup(NewInlineKeyboardRow(button))
+
+	return bot.Send(config)
+}
+
+// take removes the challenge of a member, returning nil if there is none.
+func (c *Captcha) take(key chatUserKey) *captchaChallenge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	challenge, ok := c.pending[key]
+	if !ok || challenge.timer == nil {
+		return nil
+	}
+	delete(c.pending, key)
+	challenge.timer.Stop()
+
+	return challenge
+}
+
+// solve lifts the restrictions of a member and deletes their prompt.
+// Members are unmuted even if their challenge is not pending, such as
+// after a restart, since the button can only be pressed by them.
+func (c *Captcha) solve(bot *BotAPI, prompt *Message, userID int) error {
+	chatID := prompt.Chat.ID
+	messageID := prompt.MessageID
+	if challenge := c.take(chatUserKey{chatID: chatID, userID: userID}); challenge != nil {
+		messageID = challenge.messageID
+	}
+
+	if _, err := bot.UnmuteChatMember(ChatMemberConfig{ChatID: chatID, UserID: userID}); err != nil {
+		return err
+	}
+
+	_, err := bot.DeleteMessage(DeleteMessageConfig{ChatID: chatID, MessageID: messageID})
+	return err
+}
+
+// fail bans a member who didn't solve their challenge in time and deletes
+// their prompt.
+func (c *Captcha) fail(bot *BotAPI, key chatUserKey) {
+	challenge := c.take(key)
+	if challenge == nil {
+		return
+	}
+
+	member := ChatMemberConfig{ChatID: key.chatID, UserID: key.userID}
+	if _, err := bot.BanChatMemberFor(member, c.FailBan); err != nil {
+		log.Error("captcha ban", zap.Int64("chat_id", key.chatID), zap.Int("user_id", key.userID), zap.Error(err))
+	}
+
+	if _, err := bot.DeleteMessage(DeleteMessageConfig{ChatID: key.chatID, MessageID: challenge.messageID}); err != nil {
+		log.Error("delete captcha", zap.Int64("chat_id", key.chatID), zap.Error(err))
+	}
+}
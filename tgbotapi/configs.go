@@ -2,6 +2,7 @@ package tgbotapi
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/url"
 	"strconv"
@@ -36,15 +37,85 @@ const (
 
 // Constant values for ParseMode in MessageConfig
 const (
-	ModeMarkdown = "Markdown"
-	ModeHTML     = "HTML"
+	ModeMarkdown   = "Markdown"
+	ModeMarkdownV2 = "MarkdownV2"
+	ModeHTML       = "HTML"
 )
 
+// Constant values for the type of a poll
+const (
+	PollTypeRegular = "regular"
+	PollTypeQuiz    = "quiz"
+)
+
+// Constant values for the emoji of a dice
+const (
+	DiceEmoji        = "🎲"
+	DartsEmoji       = "🎯"
+	BasketballEmoji  = "🏀"
+	FootballEmoji    = "⚽"
+	SlotMachineEmoji = "🎰"
+	BowlingEmoji     = "🎳"
+)
+
+// Constant values for the update types of AllowedUpdates
+const (
+	UpdateTypeMessage            = "message"
+	UpdateTypeEditedMessage      = "edited_message"
+	UpdateTypeChannelPost        = "channel_post"
+	UpdateTypeEditedChannelPost  = "edited_channel_post"
+	UpdateTypeInlineQuery        = "inline_query"
+	UpdateTypeChosenInlineResult = "chosen_inline_result"
+	UpdateTypeCallbackQuery      = "callback_query"
+	UpdateTypeShippingQuery      = "shipping_query"
+	UpdateTypePreCheckoutQuery   = "pre_checkout_query"
+	UpdateTypePoll               = "poll"
+	UpdateTypePollAnswer         = "poll_answer"
+	UpdateTypeMyChatMember       = "my_chat_member"
+	UpdateTypeChatMember         = "chat_member"
+	UpdateTypeChatJoinRequest    = "chat_join_request"
+)
+
+// Constant values for the type of a BotCommandScope
+const (
+	BotCommandScopeTypeDefault               = "default"
+	BotCommandScopeTypeAllPrivateChats       = "all_private_chats"
+	BotCommandScopeTypeAllGroupChats         = "all_group_chats"
+	BotCommandScopeTypeAllChatAdministrators = "all_chat_administrators"
+	BotCommandScopeTypeChat                  = "chat"
+	BotCommandScopeTypeChatAdministrators    = "chat_administrators"
+	BotCommandScopeTypeChatMember            = "chat_member"
+)
+
+// CurrencyStars is the currency of payments in Telegram Stars.
+const CurrencyStars = "XTR"
+
 // Library errors
 const (
 	// ErrBadFileType happens when you pass an unknown type
 	ErrBadFileType = "bad file type"
 	ErrBadURL      = "bad or empty url"
+	// ErrNoSender happens when an update has no chat or user to act on
+	ErrNoSender = "update has no chat or sender"
+	// ErrCallbackDataTooLong happens when encoded callback data exceeds
+	// MaxCallbackDataLength
+	ErrCallbackDataTooLong = "callback data too long"
+	ErrBadCallbackData     = "bad callback data"
+	// ErrFileSizeMismatch happens when a downloaded file is not as large
+	// as Telegram reported
+	ErrFileSizeMismatch = "downloaded file size mismatch"
+	// ErrMediaGroupSend happens when a media group is passed to Send
+	// instead of SendMediaGroup
+	ErrMediaGroupSend = "media groups must be sent with SendMediaGroup"
+	// ErrMediaGroupSize happens when a media group holds less than
+	// MinMediaGroupSize or more than MaxMediaGroupSize items
+	ErrMediaGroupSize = "media groups must hold 2 to 10 items"
+	// ErrBotAlreadyAdded happens when a token or webhook route is added to
+	// a BotManager twice
+	ErrBotAlreadyAdded = "bot already added"
+	// ErrWebhookUnroutable happens when a webhook bot is added to a
+	// BotManager with neither a URL path nor a secret token
+	ErrWebhookUnroutable = "webhook needs a URL path or a secret token"
 )
 
 // Chattable is any config type that can be sent.
@@ -66,6 +137,7 @@ type Fileable interface {
 type BaseChat struct {
 	ChatID              int64 // required
 	ChannelUsername     string
+	MessageThreadID     int
 	ReplyToMessageID    int
 	ReplyMarkup         interface{}
 	DisableNotification bool
@@ -80,6 +152,10 @@ func (chat *BaseChat) values() (url.Values, error) {
 		v.Add("chat_id", strconv.FormatInt(chat.ChatID, 10))
 	}
 
+	if chat.MessageThreadID != 0 {
+		v.Add("message_thread_id", strconv.Itoa(chat.MessageThreadID))
+	}
+
 	if chat.ReplyToMessageID != 0 {
 		v.Add("reply_to_message_id", strconv.Itoa(chat.ReplyToMessageID))
 	}
@@ -118,6 +194,10 @@ func (file BaseFile) params() (map[string]string, error) {
 		params["chat_id"] = strconv.FormatInt(file.ChatID, 10)
 	}
 
+	if file.MessageThreadID != 0 {
+		params["message_thread_id"] = strconv.Itoa(file.MessageThreadID)
+	}
+
 	if file.ReplyToMessageID != 0 {
 		params["reply_to_message_id"] = strconv.Itoa(file.ReplyToMessageID)
 	}
@@ -193,6 +273,7 @@ type MessageConfig struct {
 	BaseChat
 	Text                  string
 	ParseMode             string
+	Entities              []MessageEntity
 	DisableWebPagePreview bool
 }
 
@@ -207,6 +288,13 @@ func (config MessageConfig) values() (url.Values, error) {
 	if config.ParseMode != "" {
 		v.Add("parse_mode", config.ParseMode)
 	}
+	if len(config.Entities) > 0 {
+		data, err := json.Marshal(config.Entities)
+		if err != nil {
+			return v, err
+		}
+		v.Add("entities", string(data))
+	}
 
 	return v, nil
 }
@@ -216,6 +304,10 @@ func (config MessageConfig) method() string {
 	return "sendMessage"
 }
 
+func (config MessageConfig) newResult() *Message {
+	return new(Message)
+}
+
 // ForwardConfig contains information about a ForwardMessage request.
 type ForwardConfig struct {
 	BaseChat
@@ -240,6 +332,10 @@ func (config ForwardConfig) method() string {
 	return "forwardMessage"
 }
 
+func (config ForwardConfig) newResult() *Message {
+	return new(Message)
+}
+
 // PhotoConfig contains information about a SendPhoto request.
 type PhotoConfig struct {
 	BaseFile
@@ -281,6 +377,10 @@ func (config PhotoConfig) method() string {
 	return "sendPhoto"
 }
 
+func (config PhotoConfig) newResult() *Message {
+	return new(Message)
+}
+
 // AudioConfig contains information about a SendAudio request.
 type AudioConfig struct {
 	BaseFile
@@ -346,6 +446,10 @@ func (config AudioConfig) method() string {
 	return "sendAudio"
 }
 
+func (config AudioConfig) newResult() *Message {
+	return new(Message)
+}
+
 // DocumentConfig contains information about a SendDocument request.
 type DocumentConfig struct {
 	BaseFile
@@ -388,6 +492,10 @@ func (config DocumentConfig) method() string {
 	return "sendDocument"
 }
 
+func (config DocumentConfig) newResult() *Message {
+	return new(Message)
+}
+
 // StickerConfig contains information about a SendSticker request.
 type StickerConfig struct {
 	BaseFile
@@ -422,6 +530,10 @@ func (config StickerConfig) method() string {
 	return "sendSticker"
 }
 
+func (config StickerConfig) newResult() *Message {
+	return new(Message)
+}
+
 // VideoConfig contains information about a SendVideo request.
 type VideoConfig struct {
 	BaseFile
@@ -468,6 +580,10 @@ func (config VideoConfig) method() string {
 	return "sendVideo"
 }
 
+func (config VideoConfig) newResult() *Message {
+	return new(Message)
+}
+
 // VideoNoteConfig contains information about a SendVideoNote request.
 type VideoNoteConfig struct {
 	BaseFile
@@ -519,6 +635,10 @@ func (config VideoNoteConfig) method() string {
 	return "sendVideoNote"
 }
 
+func (config VideoNoteConfig) newResult() *Message {
+	return new(Message)
+}
+
 // VoiceConfig contains information about a SendVoice request.
 type VoiceConfig struct {
 	BaseFile
@@ -568,11 +688,18 @@ func (config VoiceConfig) method() string {
 	return "sendVoice"
 }
 
+func (config VoiceConfig) newResult() *Message {
+	return new(Message)
+}
+
 // LocationConfig contains information about a SendLocation request.
 type LocationConfig struct {
 	BaseChat
 	Latitude  float64 // required
 	Longitude float64 // required
+	// LivePeriod is how long the location can be updated with
+	// EditMessageLiveLocationConfig, in seconds.
+	LivePeriod int
 }
 
 // values returns a url.Values representation of LocationConfig.
@@ -584,6 +711,9 @@ func (config LocationConfig) values() (url.Values, error) {
 
 	v.Add("latitude", strconv.FormatFloat(config.Latitude, 'f', 6, 64))
 	v.Add("longitude", strconv.FormatFloat(config.Longitude, 'f', 6, 64))
+	if config.LivePeriod != 0 {
+		v.Add("live_period", strconv.Itoa(config.LivePeriod))
+	}
 
 	return v, nil
 }
@@ -593,6 +723,10 @@ func (config LocationConfig) method() string {
 	return "sendLocation"
 }
 
+func (config LocationConfig) newResult() *Message {
+	return new(Message)
+}
+
 // VenueConfig contains information about a SendVenue request.
 type VenueConfig struct {
 	BaseChat
@@ -624,6 +758,10 @@ func (config VenueConfig) method() string {
 	return "sendVenue"
 }
 
+func (config VenueConfig) newResult() *Message {
+	return new(Message)
+}
+
 // ContactConfig allows you to send a contact.
 type ContactConfig struct {
 	BaseChat
@@ -649,6 +787,10 @@ func (config ContactConfig) method() string {
 	return "sendContact"
 }
 
+func (config ContactConfig) newResult() *Message {
+	return new(Message)
+}
+
 // GameConfig allows you to send a game.
 type GameConfig struct {
 	BaseChat
@@ -670,6 +812,10 @@ func (config GameConfig) method() string {
 	return "sendGame"
 }
 
+func (config GameConfig) newResult() *Message {
+	return new(Message)
+}
+
 // SetGameScoreConfig allows you to update the game score in a chat.
 type SetGameScoreConfig struct {
 	UserID             int
@@ -706,6 +852,10 @@ func (config SetGameScoreConfig) method() string {
 	return "setGameScore"
 }
 
+func (config SetGameScoreConfig) newResult() *Message {
+	return new(Message)
+}
+
 // GetGameHighScoresConfig allows you to fetch the high scores for a game.
 type GetGameHighScoresConfig struct {
 	UserID          int
@@ -737,6 +887,373 @@ func (config GetGameHighScoresConfig) method() string {
 	return "getGameHighScores"
 }
 
+func (config GetGameHighScoresConfig) newResult() *[]GameHighScore {
+	return new([]GameHighScore)
+}
+
+// SendPollConfig contains information about a sendPoll request.
+type SendPollConfig struct {
+	BaseChat
+	Question              string   // required
+	Options               []string // required
+	IsAnonymous           bool
+	Type                  string
+	AllowsMultipleAnswers bool
+	CorrectOptionID       int
+	Explanation           string
+	ExplanationParseMode  string
+	OpenPeriod            int
+	CloseDate             int
+	IsClosed              bool
+}
+
+// values returns a url.Values representation of SendPollConfig.
+func (config SendPollConfig) values() (url.Values, error) {
+	v, err := config.BaseChat.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Add("question", config.Question)
+	data, err := json.Marshal(config.Options)
+	if err != nil {
+		return v, err
+	}
+	v.Add("options", string(data))
+	v.Add("is_anonymous", strconv.FormatBool(config.IsAnonymous))
+	if config.Type != "" {
+		v.Add("type", config.Type)
+	}
+	if config.AllowsMultipleAnswers {
+		v.Add("allows_multiple_answers", strconv.FormatBool(config.AllowsMultipleAnswers))
+	}
+	if config.Type == PollTypeQuiz {
+		v.Add("correct_option_id", strconv.Itoa(config.CorrectOptionID))
+	}
+	if config.Explanation != "" {
+		v.Add("explanation", config.Explanation)
+	}
+	if config.ExplanationParseMode != "" {
+		v.Add("explanation_parse_mode", config.ExplanationParseMode)
+	}
+	if config.OpenPeriod != 0 {
+		v.Add("open_period", strconv.Itoa(config.OpenPeriod))
+	}
+	if config.CloseDate != 0 {
+		v.Add("close_date", strconv.Itoa(config.CloseDate))
+	}
+	if config.IsClosed {
+		v.Add("is_closed", strconv.FormatBool(config.IsClosed))
+	}
+
+	return v, nil
+}
+
+// method returns Telegram API method name for sending Poll.
+func (config SendPollConfig) method() string {
+	return "sendPoll"
+}
+
+func (config SendPollConfig) newResult() *Message {
+	return new(Message)
+}
+
+// StopPollConfig allows you to stop a poll sent by the bot.
+type StopPollConfig struct {
+	BaseEdit
+}
+
+func (config StopPollConfig) values() (url.Values, error) {
+	return config.BaseEdit.values()
+}
+
+func (config StopPollConfig) method() string {
+	return "stopPoll"
+}
+
+func (config StopPollConfig) newResult() *Poll {
+	return new(Poll)
+}
+
+// DiceConfig contains information about a sendDice request.
+type DiceConfig struct {
+	BaseChat
+	Emoji string
+}
+
+// values returns a url.Values representation of DiceConfig.
+func (config DiceConfig) values() (url.Values, error) {
+	v, err := config.BaseChat.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.Emoji != "" {
+		v.Add("emoji", config.Emoji)
+	}
+
+	return v, nil
+}
+
+// method returns Telegram API method name for sending Dice.
+func (config DiceConfig) method() string {
+	return "sendDice"
+}
+
+func (config DiceConfig) newResult() *Message {
+	return new(Message)
+}
+
+// Constant values for the icon color of a forum topic
+const (
+	ForumTopicColorBlue   = 0x6FB9F0
+	ForumTopicColorYellow = 0xFFD67E
+	ForumTopicColorPurple = 0xCB86DB
+	ForumTopicColorGreen  = 0x8EEE98
+	ForumTopicColorPink   = 0xFF93B2
+	ForumTopicColorRed    = 0xFB6F5F
+)
+
+// BaseForum is a base type for configs acting on a forum supergroup.
+type BaseForum struct {
+	ChatID             int64
+	SuperGroupUsername string
+}
+
+// values returns a url.Values representation of BaseForum.
+func (forum BaseForum) values() (url.Values, error) {
+	v := url.Values{}
+	if forum.SuperGroupUsername != "" {
+		v.Add("chat_id", forum.SuperGroupUsername)
+	} else {
+		v.Add("chat_id", strconv.FormatInt(forum.ChatID, 10))
+	}
+
+	return v, nil
+}
+
+// CreateForumTopicConfig allows you to create a topic in a forum
+// supergroup.
+type CreateForumTopicConfig struct {
+	BaseForum
+	Name              string // required
+	IconColor         int
+	IconCustomEmojiID string
+}
+
+func (config CreateForumTopicConfig) values() (url.Values, error) {
+	v, err := config.BaseForum.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Add("name", config.Name)
+	if config.IconColor != 0 {
+		v.Add("icon_color", strconv.Itoa(config.IconColor))
+	}
+	if config.IconCustomEmojiID != "" {
+		v.Add("icon_custom_emoji_id", config.IconCustomEmojiID)
+	}
+
+	return v, nil
+}
+
+func (config CreateForumTopicConfig) method() string {
+	return "createForumTopic"
+}
+
+func (config CreateForumTopicConfig) newResult() *ForumTopic {
+	return new(ForumTopic)
+}
+
+// BaseForumTopic is a base type for configs acting on a forum topic.
+type BaseForumTopic struct {
+	BaseForum
+	MessageThreadID int // required
+}
+
+func (config BaseForumTopic) values() (url.Values, error) {
+	v, err := config.BaseForum.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Add("message_thread_id", strconv.Itoa(config.MessageThreadID))
+
+	return v, nil
+}
+
+// EditForumTopicConfig allows you to change the name and icon of a topic.
+// Empty fields are kept.
+type EditForumTopicConfig struct {
+	BaseForumTopic
+	Name              string
+	IconCustomEmojiID *string // empty removes the icon
+}
+
+func (config EditForumTopicConfig) values() (url.Values, error) {
+	v, err := config.BaseForumTopic.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.Name != "" {
+		v.Add("name", config.Name)
+	}
+	if config.IconCustomEmojiID != nil {
+		v.Add("icon_custom_emoji_id", *config.IconCustomEmojiID)
+	}
+
+	return v, nil
+}
+
+func (config EditForumTopicConfig) method() string {
+	return "editForumTopic"
+}
+
+func (config EditForumTopicConfig) newResult() *bool {
+	return new(bool)
+}
+
+// CloseForumTopicConfig allows you to close an open topic.
+type CloseForumTopicConfig struct {
+	BaseForumTopic
+}
+
+func (config CloseForumTopicConfig) method() string {
+	return "closeForumTopic"
+}
+
+func (config CloseForumTopicConfig) newResult() *bool {
+	return new(bool)
+}
+
+// ReopenForumTopicConfig allows you to reopen a closed topic.
+type ReopenForumTopicConfig struct {
+	BaseForumTopic
+}
+
+func (config ReopenForumTopicConfig) method() string {
+	return "reopenForumTopic"
+}
+
+func (config ReopenForumTopicConfig) newResult() *bool {
+	return new(bool)
+}
+
+// DeleteForumTopicConfig allows you to delete a topic along with all its
+// messages.
+type DeleteForumTopicConfig struct {
+	BaseForumTopic
+}
+
+func (config DeleteForumTopicConfig) method() string {
+	return "deleteForumTopic"
+}
+
+func (config DeleteForumTopicConfig) newResult() *bool {
+	return new(bool)
+}
+
+// BaseBotCommands is a base type for configs acting on the command list
+// of a scope and language.
+type BaseBotCommands struct {
+	Scope        *BotCommandScope // nil is the default scope
+	LanguageCode string           // empty applies to users of any language
+}
+
+// values returns a url.Values representation of BaseBotCommands.
+func (config BaseBotCommands) values() (url.Values, error) {
+	v := url.Values{}
+	if config.Scope != nil {
+		data, err := json.Marshal(config.Scope)
+		if err != nil {
+			return v, err
+		}
+		v.Add("scope", string(data))
+	}
+	if config.LanguageCode != "" {
+		v.Add("language_code", config.LanguageCode)
+	}
+
+	return v, nil
+}
+
+// SetMyCommandsConfig allows you to set the command list of the bot.
+type SetMyCommandsConfig struct {
+	BaseBotCommands
+	Commands []BotCommand
+}
+
+func (config SetMyCommandsConfig) values() (url.Values, error) {
+	v, err := config.BaseBotCommands.values()
+	if err != nil {
+		return v, err
+	}
+
+	commands := config.Commands
+	if commands == nil {
+		commands = []BotCommand{}
+	}
+	data, err := json.Marshal(commands)
+	if err != nil {
+		return v, err
+	}
+	v.Add("commands", string(data))
+
+	return v, nil
+}
+
+// jsonBody returns the body of a SetMyCommandsConfig request, sent as JSON
+// as commands and scope are objects.
+func (config SetMyCommandsConfig) jsonBody() (interface{}, error) {
+	commands := config.Commands
+	if commands == nil {
+		commands = []BotCommand{}
+	}
+
+	return struct {
+		Commands     []BotCommand     `json:"commands"`
+		Scope        *BotCommandScope `json:"scope,omitempty"`
+		LanguageCode string           `json:"language_code,omitempty"`
+	}{commands, config.Scope, config.LanguageCode}, nil
+}
+
+func (config SetMyCommandsConfig) method() string {
+	return "setMyCommands"
+}
+
+func (config SetMyCommandsConfig) newResult() *bool {
+	return new(bool)
+}
+
+// GetMyCommandsConfig allows you to get the command list of the bot.
+type GetMyCommandsConfig struct {
+	BaseBotCommands
+}
+
+func (config GetMyCommandsConfig) method() string {
+	return "getMyCommands"
+}
+
+func (config GetMyCommandsConfig) newResult() *[]BotCommand {
+	return new([]BotCommand)
+}
+
+// DeleteMyCommandsConfig allows you to delete the command list of the bot,
+// so that users see the list of a broader scope.
+type DeleteMyCommandsConfig struct {
+	BaseBotCommands
+}
+
+func (config DeleteMyCommandsConfig) method() string {
+	return "deleteMyCommands"
+}
+
+func (config DeleteMyCommandsConfig) newResult() *bool {
+	return new(bool)
+}
+
 // ChatActionConfig contains information about a SendChatAction request.
 type ChatActionConfig struct {
 	BaseChat
@@ -758,11 +1275,16 @@ func (config ChatActionConfig) method() string {
 	return "sendChatAction"
 }
 
+func (config ChatActionConfig) newResult() *bool {
+	return new(bool)
+}
+
 // EditMessageTextConfig allows you to modify the text in a message.
 type EditMessageTextConfig struct {
 	BaseEdit
 	Text                  string
 	ParseMode             string
+	Entities              []MessageEntity
 	DisableWebPagePreview bool
 }
 
@@ -775,6 +1297,13 @@ func (config EditMessageTextConfig) values() (url.Values, error) {
 	v.Add("text", config.Text)
 	v.Add("parse_mode", config.ParseMode)
 	v.Add("disable_web_page_preview", strconv.FormatBool(config.DisableWebPagePreview))
+	if len(config.Entities) > 0 {
+		data, err := json.Marshal(config.Entities)
+		if err != nil {
+			return v, err
+		}
+		v.Add("entities", string(data))
+	}
 
 	return v, nil
 }
@@ -783,6 +1312,10 @@ func (config EditMessageTextConfig) method() string {
 	return "editMessageText"
 }
 
+func (config EditMessageTextConfig) newResult() *Message {
+	return new(Message)
+}
+
 // EditMessageCaptionConfig allows you to modify the caption of a message.
 type EditMessageCaptionConfig struct {
 	BaseEdit
@@ -801,6 +1334,10 @@ func (config EditMessageCaptionConfig) method() string {
 	return "editMessageCaption"
 }
 
+func (config EditMessageCaptionConfig) newResult() *Message {
+	return new(Message)
+}
+
 // EditMessageReplyMarkupConfig allows you to modify the reply markup
 // of a message.
 type EditMessageReplyMarkupConfig struct {
@@ -815,31 +1352,312 @@ func (config EditMessageReplyMarkupConfig) method() string {
 	return "editMessageReplyMarkup"
 }
 
-// UserProfilePhotosConfig contains information about a
-// GetUserProfilePhotos request.
-type UserProfilePhotosConfig struct {
-	UserID int
-	Offset int
-	Limit  int
+func (config EditMessageReplyMarkupConfig) newResult() *Message {
+	return new(Message)
 }
 
-// FileConfig has information about a file hosted on Telegram.
-type FileConfig struct {
-	FileID string
+// EditMessageMediaConfig allows you to replace the photo, video, audio
+// file or document of a message.
+type EditMessageMediaConfig struct {
+	BaseEdit
+	// Media is an InputMediaPhoto, InputMediaVideo, InputMediaAudio or
+	// InputMediaDocument, which may hold a file to upload.
+	Media interface{}
 }
 
-// UpdateConfig contains information about a GetUpdates request.
-type UpdateConfig struct {
-	Offset  int
-	Limit   int
-	Timeout int
-}
+// values returns a url.Values representation of EditMessageMediaConfig.
+// Media must not hold a file to upload.
+func (config EditMessageMediaConfig) values() (url.Values, error) {
+	v, err := config.BaseEdit.values()
+	if err != nil {
+		return v, err
+	}
 
-// WebhookConfig contains information about a SetWebhook request.
-type WebhookConfig struct {
-	URL            *url.URL
-	Certificate    interface{}
-	MaxConnections int
+	data, err := json.Marshal(config.Media)
+	if err != nil {
+		return v, err
+	}
+	v.Add("media", string(data))
+
+	return v, nil
+}
+
+// params returns a map[string]string representation of
+// EditMessageMediaConfig, along with the file to upload, if any.
+func (config EditMessageMediaConfig) params() (map[string]string, []RequestFile, error) {
+	v, err := config.BaseEdit.values()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := make(map[string]string)
+	for key := range v {
+		params[key] = v.Get(key)
+	}
+
+	var files []RequestFile
+	media := interface{}(config.Media)
+	if m, ok := config.Media.(inputMedia); ok && m.mediaFile() != nil {
+		file := RequestFile{Name: "file-0", File: m.mediaFile()}
+		files = append(files, file)
+
+		media, err = attachMedia(config.Media, "attach://"+file.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := json.Marshal(media)
+	if err != nil {
+		return nil, nil, err
+	}
+	params["media"] = string(data)
+
+	return params, files, nil
+}
+
+func (config EditMessageMediaConfig) method() string {
+	return "editMessageMedia"
+}
+
+func (config EditMessageMediaConfig) newResult() *Message {
+	return new(Message)
+}
+
+// EditMessageLiveLocationConfig allows you to move a live location.
+type EditMessageLiveLocationConfig struct {
+	BaseEdit
+	Latitude             float64 // required
+	Longitude            float64 // required
+	HorizontalAccuracy   float64
+	Heading              int
+	ProximityAlertRadius int
+}
+
+func (config EditMessageLiveLocationConfig) values() (url.Values, error) {
+	v, err := config.BaseEdit.values()
+	if err != nil {
+		return v, err
+	}
+
+	v.Add("latitude", strconv.FormatFloat(config.Latitude, 'f', 6, 64))
+	v.Add("longitude", strconv.FormatFloat(config.Longitude, 'f', 6, 64))
+	if config.HorizontalAccuracy != 0 {
+		v.Add("horizontal_accuracy", strconv.FormatFloat(config.HorizontalAccuracy, 'f', -1, 64))
+	}
+	if config.Heading != 0 {
+		v.Add("heading", strconv.Itoa(config.Heading))
+	}
+	if config.ProximityAlertRadius != 0 {
+		v.Add("proximity_alert_radius", strconv.Itoa(config.ProximityAlertRadius))
+	}
+
+	return v, nil
+}
+
+func (config EditMessageLiveLocationConfig) method() string {
+	return "editMessageLiveLocation"
+}
+
+func (config EditMessageLiveLocationConfig) newResult() *Message {
+	return new(Message)
+}
+
+// StopMessageLiveLocationConfig allows you to stop updating a live
+// location.
+type StopMessageLiveLocationConfig struct {
+	BaseEdit
+}
+
+func (config StopMessageLiveLocationConfig) values() (url.Values, error) {
+	return config.BaseEdit.values()
+}
+
+func (config StopMessageLiveLocationConfig) method() string {
+	return "stopMessageLiveLocation"
+}
+
+func (config StopMessageLiveLocationConfig) newResult() *Message {
+	return new(Message)
+}
+
+// UserProfilePhotosConfig contains information about a
+// GetUserProfilePhotos request.
+type UserProfilePhotosConfig struct {
+	UserID int
+	Offset int
+	Limit  int
+}
+
+func (config UserProfilePhotosConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("user_id", strconv.Itoa(config.UserID))
+	if config.Offset != 0 {
+		v.Add("offset", strconv.Itoa(config.Offset))
+	}
+	if config.Limit != 0 {
+		v.Add("limit", strconv.Itoa(config.Limit))
+	}
+
+	return v, nil
+}
+
+func (config UserProfilePhotosConfig) method() string {
+	return "getUserProfilePhotos"
+}
+
+func (config UserProfilePhotosConfig) newResult() *UserProfilePhotos {
+	return new(UserProfilePhotos)
+}
+
+// FileConfig has information about a file hosted on Telegram.
+type FileConfig struct {
+	FileID string
+}
+
+func (config FileConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("file_id", config.FileID)
+
+	return v, nil
+}
+
+func (config FileConfig) method() string {
+	return "getFile"
+}
+
+func (config FileConfig) newResult() *File {
+	return new(File)
+}
+
+// UpdateConfig contains information about a GetUpdates request.
+type UpdateConfig struct {
+	Offset  int
+	Limit   int
+	Timeout int
+	// AllowedUpdates lists the update types to receive, such as
+	// UpdateTypeChatMember. Nil keeps the previous setting.
+	AllowedUpdates []string
+}
+
+func (config UpdateConfig) values() (url.Values, error) {
+	v := url.Values{}
+	if config.Offset != 0 {
+		v.Add("offset", strconv.Itoa(config.Offset))
+	}
+	if config.Limit > 0 {
+		v.Add("limit", strconv.Itoa(config.Limit))
+	}
+	if config.Timeout > 0 {
+		v.Add("timeout", strconv.Itoa(config.Timeout))
+	}
+	if config.AllowedUpdates != nil {
+		data, err := json.Marshal(config.AllowedUpdates)
+		if err != nil {
+			return v, err
+		}
+		v.Add("allowed_updates", string(data))
+	}
+
+	return v, nil
+}
+
+func (config UpdateConfig) method() string {
+	return "getUpdates"
+}
+
+func (config UpdateConfig) newResult() *[]Update {
+	return new([]Update)
+}
+
+// WebhookConfig contains information about a SetWebhook request.
+type WebhookConfig struct {
+	URL                *url.URL
+	Certificate        interface{}
+	IPAddress          string
+	MaxConnections     int
+	AllowedUpdates     []string
+	DropPendingUpdates bool
+	SecretToken        string
+}
+
+// params returns a map[string]string representation of WebhookConfig.
+func (config WebhookConfig) params() (map[string]string, error) {
+	params := make(map[string]string)
+
+	params["url"] = config.URL.String()
+	if config.IPAddress != "" {
+		params["ip_address"] = config.IPAddress
+	}
+	if config.MaxConnections != 0 {
+		params["max_connections"] = strconv.Itoa(config.MaxConnections)
+	}
+	if config.AllowedUpdates != nil {
+		data, err := json.Marshal(config.AllowedUpdates)
+		if err != nil {
+			return params, err
+		}
+		params["allowed_updates"] = string(data)
+	}
+	if config.DropPendingUpdates {
+		params["drop_pending_updates"] = strconv.FormatBool(config.DropPendingUpdates)
+	}
+	if config.SecretToken != "" {
+		params["secret_token"] = config.SecretToken
+	}
+
+	return params, nil
+}
+
+func (config WebhookConfig) values() (url.Values, error) {
+	params, err := config.params()
+	if err != nil {
+		return nil, err
+	}
+
+	v := url.Values{}
+	for key, value := range params {
+		v.Add(key, value)
+	}
+
+	return v, nil
+}
+
+func (config WebhookConfig) method() string {
+	return "setWebhook"
+}
+
+func (config WebhookConfig) name() string {
+	return "certificate"
+}
+
+func (config WebhookConfig) getFile() interface{} {
+	return config.Certificate
+}
+
+// useExistingFile is true without a certificate to upload.
+func (config WebhookConfig) useExistingFile() bool {
+	return config.Certificate == nil
+}
+
+func (config WebhookConfig) newResult() *bool {
+	return new(bool)
+}
+
+// methodRequest is a request of a method taking no parameters, named by
+// the string.
+type methodRequest[T any] string
+
+func (config methodRequest[T]) values() (url.Values, error) {
+	return url.Values{}, nil
+}
+
+func (config methodRequest[T]) method() string {
+	return string(config)
+}
+
+func (config methodRequest[T]) newResult() *T {
+	return new(T)
 }
 
 // FileBytes contains information about a set of bytes to upload
@@ -858,6 +1676,133 @@ type FileReader struct {
 	Size   int64
 }
 
+// RequestFile is a file to upload along with a request.
+type RequestFile struct {
+	// Name is the name of the request field holding the file.
+	Name string
+	// File is a string to a file path, FileBytes, FileReader or url.URL.
+	File interface{}
+}
+
+// MediaGroupConfig contains information about a sendMediaGroup request.
+//
+// Media holds MinMediaGroupSize to MaxMediaGroupSize InputMediaPhoto,
+// InputMediaVideo, InputMediaDocument or InputMediaAudio items. Documents
+// and audio files can only be grouped with items of the same type.
+//
+// Use BotAPI.SendMediaGroup to send it, as it results in several messages.
+type MediaGroupConfig struct {
+	BaseChat
+	Media []interface{}
+}
+
+// values returns a url.Values representation of MediaGroupConfig. Media
+// items must not hold files to upload.
+func (config MediaGroupConfig) values() (url.Values, error) {
+	if err := config.checkSize(); err != nil {
+		return url.Values{}, err
+	}
+
+	v, err := config.BaseChat.values()
+	if err != nil {
+		return v, err
+	}
+
+	data, err := json.Marshal(config.Media)
+	if err != nil {
+		return v, err
+	}
+	v.Add("media", string(data))
+
+	return v, nil
+}
+
+// params returns a map[string]string representation of MediaGroupConfig,
+// along with the files to upload. Media items holding a file refer to it
+// with the attach:// syntax.
+func (config MediaGroupConfig) params() (map[string]string, []RequestFile, error) {
+	if err := config.checkSize(); err != nil {
+		return nil, nil, err
+	}
+
+	v, err := config.BaseChat.values()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := make(map[string]string)
+	for key := range v {
+		params[key] = v.Get(key)
+	}
+
+	var files []RequestFile
+	media := make([]interface{}, len(config.Media))
+	for i, item := range config.Media {
+		media[i] = item
+
+		m, ok := item.(inputMedia)
+		if !ok || m.mediaFile() == nil {
+			continue
+		}
+
+		file := RequestFile{Name: "file-" + strconv.Itoa(i), File: m.mediaFile()}
+		files = append(files, file)
+
+		attached, err := attachMedia(item, "attach://"+file.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		media[i] = attached
+	}
+
+	data, err := json.Marshal(media)
+	if err != nil {
+		return nil, nil, err
+	}
+	params["media"] = string(data)
+
+	return params, files, nil
+}
+
+// method returns Telegram API method name for sending a media group.
+func (config MediaGroupConfig) method() string {
+	return "sendMediaGroup"
+}
+
+// checkSize returns an error if Media holds too few or too many items.
+func (config MediaGroupConfig) checkSize() error {
+	if len(config.Media) < MinMediaGroupSize || len(config.Media) > MaxMediaGroupSize {
+		return errors.New(ErrMediaGroupSize)
+	}
+
+	return nil
+}
+
+func (config MediaGroupConfig) newResult() *[]Message {
+	return new([]Message)
+}
+
+// attachMedia returns the JSON representation of a media item, with its
+// media field replaced.
+func attachMedia(item interface{}, media string) (json.RawMessage, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	fields["media"], err = json.Marshal(media)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
 // InlineConfig contains information on making an InlineQuery response.
 type InlineConfig struct {
 	InlineQueryID     string        `json:"inline_query_id"`
@@ -865,8 +1810,54 @@ type InlineConfig struct {
 	CacheTime         int           `json:"cache_time"`
 	IsPersonal        bool          `json:"is_personal"`
 	NextOffset        string        `json:"next_offset"`
-	SwitchPMText      string        `json:"switch_pm_text"`
-	SwitchPMParameter string        `json:"switch_pm_parameter"`
+	SwitchPMText      string        `json:"switch_pm_text,omitempty"`
+	SwitchPMParameter string        `json:"switch_pm_parameter,omitempty"`
+	// Button replaces SwitchPMText and SwitchPMParameter.
+	Button *InlineQueryResultsButton `json:"button,omitempty"`
+}
+
+func (config InlineConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("inline_query_id", config.InlineQueryID)
+	v.Add("cache_time", strconv.Itoa(config.CacheTime))
+	v.Add("is_personal", strconv.FormatBool(config.IsPersonal))
+	v.Add("next_offset", config.NextOffset)
+	data, err := json.Marshal(config.Results)
+	if err != nil {
+		return v, err
+	}
+	v.Add("results", string(data))
+	if config.SwitchPMText != "" {
+		v.Add("switch_pm_text", config.SwitchPMText)
+		v.Add("switch_pm_parameter", config.SwitchPMParameter)
+	}
+	if config.Button != nil {
+		data, err := json.Marshal(config.Button)
+		if err != nil {
+			return v, err
+		}
+		v.Add("button", string(data))
+	}
+
+	return v, nil
+}
+
+// jsonBody returns the body of an InlineConfig request, sent as JSON as
+// results are objects.
+func (config InlineConfig) jsonBody() (interface{}, error) {
+	if config.Results == nil {
+		config.Results = []interface{}{}
+	}
+
+	return config, nil
+}
+
+func (config InlineConfig) method() string {
+	return "answerInlineQuery"
+}
+
+func (config InlineConfig) newResult() *bool {
+	return new(bool)
 }
 
 // CallbackConfig contains information on making a CallbackQuery response.
@@ -878,6 +1869,29 @@ type CallbackConfig struct {
 	CacheTime       int    `json:"cache_time"`
 }
 
+func (config CallbackConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("callback_query_id", config.CallbackQueryID)
+	if config.Text != "" {
+		v.Add("text", config.Text)
+	}
+	v.Add("show_alert", strconv.FormatBool(config.ShowAlert))
+	if config.URL != "" {
+		v.Add("url", config.URL)
+	}
+	v.Add("cache_time", strconv.Itoa(config.CacheTime))
+
+	return v, nil
+}
+
+func (config CallbackConfig) method() string {
+	return "answerCallbackQuery"
+}
+
+func (config CallbackConfig) newResult() *bool {
+	return new(bool)
+}
+
 // ChatMemberConfig contains information about a user in a chat for use
 // with administrative functions such as kicking or unbanning a user.
 type ChatMemberConfig struct {
@@ -887,12 +1901,97 @@ type ChatMemberConfig struct {
 	UserID             int
 }
 
+// values returns a url.Values representation of ChatMemberConfig.
+func (config ChatMemberConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	if config.SuperGroupUsername != "" {
+		v.Add("chat_id", config.SuperGroupUsername)
+	} else if config.ChannelUsername != "" {
+		v.Add("chat_id", config.ChannelUsername)
+	} else {
+		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
+	}
+	v.Add("user_id", strconv.Itoa(config.UserID))
+
+	return v, nil
+}
+
+// chatMemberRequest is a request of a method taking only a ChatMemberConfig.
+type chatMemberRequest struct {
+	ChatMemberConfig
+	name string
+}
+
+func (config chatMemberRequest) method() string {
+	return config.name
+}
+
+func (config chatMemberRequest) newResult() *bool {
+	return new(bool)
+}
+
 // KickChatMemberConfig contains extra fields to kick user
 type KickChatMemberConfig struct {
 	ChatMemberConfig
 	UntilDate int64
 }
 
+func (config KickChatMemberConfig) values() (url.Values, error) {
+	v, err := config.ChatMemberConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.UntilDate != 0 {
+		v.Add("until_date", strconv.FormatInt(config.UntilDate, 10))
+	}
+
+	return v, nil
+}
+
+func (config KickChatMemberConfig) method() string {
+	return "kickChatMember"
+}
+
+func (config KickChatMemberConfig) newResult() *bool {
+	return new(bool)
+}
+
+// BanChatMemberConfig contains fields to ban a user from a chat.
+type BanChatMemberConfig struct {
+	ChatMemberConfig
+	// UntilDate is when the user is unbanned, as a Unix time. 0 or a date
+	// less than 30 seconds or more than 366 days away bans forever.
+	UntilDate int64
+	// RevokeMessages deletes all messages of the user in the chat.
+	RevokeMessages bool
+}
+
+func (config BanChatMemberConfig) values() (url.Values, error) {
+	v, err := config.ChatMemberConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.UntilDate != 0 {
+		v.Add("until_date", strconv.FormatInt(config.UntilDate, 10))
+	}
+	if config.RevokeMessages {
+		v.Add("revoke_messages", strconv.FormatBool(config.RevokeMessages))
+	}
+
+	return v, nil
+}
+
+func (config BanChatMemberConfig) method() string {
+	return "banChatMember"
+}
+
+func (config BanChatMemberConfig) newResult() *bool {
+	return new(bool)
+}
+
 // RestrictChatMemberConfig contains fields to restrict members of chat
 type RestrictChatMemberConfig struct {
 	ChatMemberConfig
@@ -901,6 +2000,50 @@ type RestrictChatMemberConfig struct {
 	CanSendMediaMessages  *bool
 	CanSendOtherMessages  *bool
 	CanAddWebPagePreviews *bool
+	// Permissions are sent instead of the deprecated fields above when
+	// set.
+	Permissions *ChatPermissions
+}
+
+func (config RestrictChatMemberConfig) values() (url.Values, error) {
+	v, err := config.ChatMemberConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.Permissions != nil {
+		data, err := json.Marshal(config.Permissions)
+		if err != nil {
+			return v, err
+		}
+		v.Add("permissions", string(data))
+	} else {
+		if config.CanSendMessages != nil {
+			v.Add("can_send_messages", strconv.FormatBool(*config.CanSendMessages))
+		}
+		if config.CanSendMediaMessages != nil {
+			v.Add("can_send_media_messages", strconv.FormatBool(*config.CanSendMediaMessages))
+		}
+		if config.CanSendOtherMessages != nil {
+			v.Add("can_send_other_messages", strconv.FormatBool(*config.CanSendOtherMessages))
+		}
+		if config.CanAddWebPagePreviews != nil {
+			v.Add("can_add_web_page_previews", strconv.FormatBool(*config.CanAddWebPagePreviews))
+		}
+	}
+	if config.UntilDate != 0 {
+		v.Add("until_date", strconv.FormatInt(config.UntilDate, 10))
+	}
+
+	return v, nil
+}
+
+func (config RestrictChatMemberConfig) method() string {
+	return "restrictChatMember"
+}
+
+func (config RestrictChatMemberConfig) newResult() *bool {
+	return new(bool)
 }
 
 // PromoteChatMemberConfig contains fields to promote members of chat
@@ -916,12 +2059,115 @@ type PromoteChatMemberConfig struct {
 	CanPromoteMembers  *bool
 }
 
+func (config PromoteChatMemberConfig) values() (url.Values, error) {
+	v, err := config.ChatMemberConfig.values()
+	if err != nil {
+		return v, err
+	}
+
+	if config.CanChangeInfo != nil {
+		v.Add("can_change_info", strconv.FormatBool(*config.CanChangeInfo))
+	}
+	if config.CanPostMessages != nil {
+		v.Add("can_post_messages", strconv.FormatBool(*config.CanPostMessages))
+	}
+	if config.CanEditMessages != nil {
+		v.Add("can_edit_messages", strconv.FormatBool(*config.CanEditMessages))
+	}
+	if config.CanDeleteMessages != nil {
+		v.Add("can_delete_messages", strconv.FormatBool(*config.CanDeleteMessages))
+	}
+	if config.CanInviteUsers != nil {
+		v.Add("can_invite_users", strconv.FormatBool(*config.CanInviteUsers))
+	}
+	if config.CanRestrictMembers != nil {
+		v.Add("can_restrict_members", strconv.FormatBool(*config.CanRestrictMembers))
+	}
+	if config.CanPinMessages != nil {
+		v.Add("can_pin_messages", strconv.FormatBool(*config.CanPinMessages))
+	}
+	if config.CanPromoteMembers != nil {
+		v.Add("can_promote_members", strconv.FormatBool(*config.CanPromoteMembers))
+	}
+
+	return v, nil
+}
+
+func (config PromoteChatMemberConfig) method() string {
+	return "promoteChatMember"
+}
+
+func (config PromoteChatMemberConfig) newResult() *bool {
+	return new(bool)
+}
+
 // ChatConfig contains information about getting information on a chat.
 type ChatConfig struct {
 	ChatID             int64
 	SuperGroupUsername string
 }
 
+// values returns a url.Values representation of ChatConfig.
+func (config ChatConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	if config.SuperGroupUsername == "" {
+		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
+	} else {
+		v.Add("chat_id", config.SuperGroupUsername)
+	}
+
+	return v, nil
+}
+
+// chatRequest is a request of a method taking only a ChatConfig.
+type chatRequest[T any] struct {
+	ChatConfig
+	name string
+}
+
+func (config chatRequest[T]) method() string {
+	return config.name
+}
+
+func (config chatRequest[T]) newResult() *T {
+	return new(T)
+}
+
+// SetChatPermissionsConfig allows you to set the default permissions of
+// the members of a chat.
+type SetChatPermissionsConfig struct {
+	ChatID             int64
+	SuperGroupUsername string
+	Permissions        ChatPermissions
+}
+
+func (config SetChatPermissionsConfig) values() (url.Values, error) {
+	v := url.Values{}
+
+	if config.SuperGroupUsername == "" {
+		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
+	} else {
+		v.Add("chat_id", config.SuperGroupUsername)
+	}
+
+	data, err := json.Marshal(config.Permissions)
+	if err != nil {
+		return v, err
+	}
+	v.Add("permissions", string(data))
+
+	return v, nil
+}
+
+func (config SetChatPermissionsConfig) method() string {
+	return "setChatPermissions"
+}
+
+func (config SetChatPermissionsConfig) newResult() *bool {
+	return new(bool)
+}
+
 // ChatConfigWithUser contains information about getting information on
 // a specific user within a chat.
 type ChatConfigWithUser struct {
@@ -930,25 +2176,55 @@ type ChatConfigWithUser struct {
 	UserID             int
 }
 
+func (config ChatConfigWithUser) values() (url.Values, error) {
+	v := url.Values{}
+
+	if config.SuperGroupUsername == "" {
+		v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
+	} else {
+		v.Add("chat_id", config.SuperGroupUsername)
+	}
+	v.Add("user_id", strconv.Itoa(config.UserID))
+
+	return v, nil
+}
+
+func (config ChatConfigWithUser) method() string {
+	return "getChatMember"
+}
+
+func (config ChatConfigWithUser) newResult() *ChatMember {
+	return new(ChatMember)
+}
+
 // InvoiceConfig contains information for sendInvoice request.
+//
+// Payments in Telegram Stars use CurrencyStars, an empty ProviderToken and
+// a single price. They don't support tips, the Need and Send flags or
+// IsFlexible.
 type InvoiceConfig struct {
 	BaseChat
-	Title               string          // required
-	Description         string          // required
-	Payload             string          // required
-	ProviderToken       string          // required
-	StartParameter      string          // required
-	Currency            string          // required
-	Prices              *[]LabeledPrice // required
-	PhotoURL            string
-	PhotoSize           int
-	PhotoWidth          int
-	PhotoHeight         int
-	NeedName            bool
-	NeedPhoneNumber     bool
-	NeedEmail           bool
-	NeedShippingAddress bool
-	IsFlexible          bool
+	Title                     string // required
+	Description               string // required
+	Payload                   string // required
+	ProviderToken             string // required, except for Telegram Stars
+	StartParameter            string
+	Currency                  string          // required
+	Prices                    *[]LabeledPrice // required
+	PhotoURL                  string
+	PhotoSize                 int
+	PhotoWidth                int
+	PhotoHeight               int
+	NeedName                  bool
+	NeedPhoneNumber           bool
+	NeedEmail                 bool
+	NeedShippingAddress       bool
+	IsFlexible                bool
+	MaxTipAmount              int
+	SuggestedTipAmounts       []int
+	ProviderData              string // JSON
+	SendPhoneNumberToProvider bool
+	SendEmailToProvider       bool
 }
 
 func (config InvoiceConfig) values() (url.Values, error) {
@@ -956,17 +2232,107 @@ func (config InvoiceConfig) values() (url.Values, error) {
 	if err != nil {
 		return v, err
 	}
+	invoice, err := config.invoiceLink().values()
+	if err != nil {
+		return v, err
+	}
+	for key, values := range invoice {
+		v[key] = values
+	}
+	if config.StartParameter != "" {
+		v.Add("start_parameter", config.StartParameter)
+	}
+
+	return v, nil
+}
+
+// invoiceLink returns the createInvoiceLink request for the same invoice,
+// whose values are shared with sendInvoice.
+func (config InvoiceConfig) invoiceLink() CreateInvoiceLinkConfig {
+	return CreateInvoiceLinkConfig{
+		Title:                     config.Title,
+		Description:               config.Description,
+		Payload:                   config.Payload,
+		ProviderToken:             config.ProviderToken,
+		Currency:                  config.Currency,
+		Prices:                    config.Prices,
+		PhotoURL:                  config.PhotoURL,
+		PhotoSize:                 config.PhotoSize,
+		PhotoWidth:                config.PhotoWidth,
+		PhotoHeight:               config.PhotoHeight,
+		NeedName:                  config.NeedName,
+		NeedPhoneNumber:           config.NeedPhoneNumber,
+		NeedEmail:                 config.NeedEmail,
+		NeedShippingAddress:       config.NeedShippingAddress,
+		IsFlexible:                config.IsFlexible,
+		MaxTipAmount:              config.MaxTipAmount,
+		SuggestedTipAmounts:       config.SuggestedTipAmounts,
+		ProviderData:              config.ProviderData,
+		SendPhoneNumberToProvider: config.SendPhoneNumberToProvider,
+		SendEmailToProvider:       config.SendEmailToProvider,
+	}
+}
+
+func (config InvoiceConfig) method() string {
+	return "sendInvoice"
+}
+
+func (config InvoiceConfig) newResult() *Message {
+	return new(Message)
+}
+
+// CreateInvoiceLinkConfig contains information for createInvoiceLink
+// request. Telegram Stars are used like in InvoiceConfig.
+type CreateInvoiceLinkConfig struct {
+	Title                     string          // required
+	Description               string          // required
+	Payload                   string          // required
+	ProviderToken             string          // required, except for Telegram Stars
+	Currency                  string          // required
+	Prices                    *[]LabeledPrice // required
+	PhotoURL                  string
+	PhotoSize                 int
+	PhotoWidth                int
+	PhotoHeight               int
+	NeedName                  bool
+	NeedPhoneNumber           bool
+	NeedEmail                 bool
+	NeedShippingAddress       bool
+	IsFlexible                bool
+	MaxTipAmount              int
+	SuggestedTipAmounts       []int
+	ProviderData              string // JSON
+	SendPhoneNumberToProvider bool
+	SendEmailToProvider       bool
+}
+
+func (config CreateInvoiceLinkConfig) values() (url.Values, error) {
+	v := url.Values{}
 	v.Add("title", config.Title)
 	v.Add("description", config.Description)
 	v.Add("payload", config.Payload)
-	v.Add("provider_token", config.ProviderToken)
-	v.Add("start_parameter", config.StartParameter)
+	if config.Currency != CurrencyStars {
+		v.Add("provider_token", config.ProviderToken)
+	}
 	v.Add("currency", config.Currency)
 	data, err := json.Marshal(config.Prices)
 	if err != nil {
 		return v, err
 	}
 	v.Add("prices", string(data))
+	if config.MaxTipAmount != 0 {
+		v.Add("max_tip_amount", strconv.Itoa(config.MaxTipAmount))
+	}
+	if len(config.SuggestedTipAmounts) != 0 {
+		data, err := json.Marshal(config.SuggestedTipAmounts)
+		if err != nil {
+			return v, err
+		}
+		v.Add("suggested_tip_amounts", string(data))
+	}
+	if config.ProviderData != "" {
+		v.Add("provider_data", config.ProviderData)
+	}
 	if config.PhotoURL != "" {
 		v.Add("photo_url", config.PhotoURL)
 	}
@@ -991,6 +2357,12 @@ func (config InvoiceConfig) values() (url.Values, error) {
 	if config.NeedShippingAddress != false {
 		v.Add("need_shipping_address", strconv.FormatBool(config.NeedShippingAddress))
 	}
+	if config.SendPhoneNumberToProvider != false {
+		v.Add("send_phone_number_to_provider", strconv.FormatBool(config.SendPhoneNumberToProvider))
+	}
+	if config.SendEmailToProvider != false {
+		v.Add("send_email_to_provider", strconv.FormatBool(config.SendEmailToProvider))
+	}
 	if config.IsFlexible != false {
 		v.Add("is_flexible", strconv.FormatBool(config.IsFlexible))
 	}
@@ -998,8 +2370,35 @@ func (config InvoiceConfig) values() (url.Values, error) {
 	return v, nil
 }
 
-func (config InvoiceConfig) method() string {
-	return "sendInvoice"
+func (config CreateInvoiceLinkConfig) method() string {
+	return "createInvoiceLink"
+}
+
+func (config CreateInvoiceLinkConfig) newResult() *string {
+	return new(string)
+}
+
+// RefundStarPaymentConfig contains information for refundStarPayment
+// request.
+type RefundStarPaymentConfig struct {
+	UserID                  int    // required
+	TelegramPaymentChargeID string // required
+}
+
+func (config RefundStarPaymentConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("user_id", strconv.Itoa(config.UserID))
+	v.Add("telegram_payment_charge_id", config.TelegramPaymentChargeID)
+
+	return v, nil
+}
+
+func (config RefundStarPaymentConfig) method() string {
+	return "refundStarPayment"
+}
+
+func (config RefundStarPaymentConfig) newResult() *bool {
+	return new(bool)
 }
 
 // ShippingConfig contains information for answerShippingQuery request.
@@ -1010,6 +2409,31 @@ type ShippingConfig struct {
 	ErrorMessage    string
 }
 
+func (config ShippingConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("shipping_query_id", config.ShippingQueryID)
+	v.Add("ok", strconv.FormatBool(config.OK))
+	if config.OK {
+		data, err := json.Marshal(config.ShippingOptions)
+		if err != nil {
+			return v, err
+		}
+		v.Add("shipping_options", string(data))
+	} else {
+		v.Add("error_message", config.ErrorMessage)
+	}
+
+	return v, nil
+}
+
+func (config ShippingConfig) method() string {
+	return "answerShippingQuery"
+}
+
+func (config ShippingConfig) newResult() *bool {
+	return new(bool)
+}
+
 // PreCheckoutConfig conatins information for answerPreCheckoutQuery request.
 type PreCheckoutConfig struct {
 	PreCheckoutQueryID string // required
@@ -1017,6 +2441,55 @@ type PreCheckoutConfig struct {
 	ErrorMessage       string
 }
 
+func (config PreCheckoutConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("pre_checkout_query_id", config.PreCheckoutQueryID)
+	v.Add("ok", strconv.FormatBool(config.OK))
+	if !config.OK {
+		v.Add("error", config.ErrorMessage)
+	}
+
+	return v, nil
+}
+
+func (config PreCheckoutConfig) method() string {
+	return "answerPreCheckoutQuery"
+}
+
+func (config PreCheckoutConfig) newResult() *bool {
+	return new(bool)
+}
+
+// DeleteMessagesConfig contains information of messages in a chat to
+// delete at once.
+type DeleteMessagesConfig struct {
+	ChatID int64
+	// MessageIDs holds 1 to MaxDeleteMessages messages. Messages that
+	// can't be deleted are skipped.
+	MessageIDs []int
+}
+
+func (config DeleteMessagesConfig) values() (url.Values, error) {
+	v := url.Values{}
+	v.Add("chat_id", strconv.FormatInt(config.ChatID, 10))
+
+	data, err := json.Marshal(config.MessageIDs)
+	if err != nil {
+		return v, err
+	}
+	v.Add("message_ids", string(data))
+
+	return v, nil
+}
+
+func (config DeleteMessagesConfig) method() string {
+	return "deleteMessages"
+}
+
+func (config DeleteMessagesConfig) newResult() *bool {
+	return new(bool)
+}
+
 // DeleteMessageConfig contains information of a message in a chat to delete.
 type DeleteMessageConfig struct {
 	ChatID    int64
@@ -1027,6 +2500,10 @@ func (config DeleteMessageConfig) method() string {
 	return "deleteMessage"
 }
 
+func (config DeleteMessageConfig) newResult() *bool {
+	return new(bool)
+}
+
 func (config DeleteMessageConfig) values() (url.Values, error) {
 	v := url.Values{}
 
@@ -1047,6 +2524,10 @@ func (config PinChatMessageConfig) method() string {
 	return "pinChatMessage"
 }
 
+func (config PinChatMessageConfig) newResult() *bool {
+	return new(bool)
+}
+
 func (config PinChatMessageConfig) values() (url.Values, error) {
 	v := url.Values{}
 
@@ -1066,6 +2547,10 @@ func (config UnpinChatMessageConfig) method() string {
 	return "unpinChatMessage"
 }
 
+func (config UnpinChatMessageConfig) newResult() *bool {
+	return new(bool)
+}
+
 func (config UnpinChatMessageConfig) values() (url.Values, error) {
 	v := url.Values{}
 
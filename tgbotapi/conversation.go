@@ -0,0 +1,511 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byepp/util/fileutil"
+	"github.com/byepp/util/jsonutil"
+	"go.uber.org/zap"
+)
+
+// DefaultConversationTimeout is how long a conversation may stay idle
+// before it is abandoned.
+const DefaultConversationTimeout = 10 * time.Minute
+
+// DefaultCancelCommand is the command ending any active conversation.
+const DefaultCancelCommand = "cancel"
+
+// ConversationState is the stored state of a conversation.
+type ConversationState struct {
+	Step      string            `json:"step"`
+	Data      map[string]string `json:"data,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ConversationStore persists conversation states, keyed by chat and user.
+//
+// Implementations must be safe for concurrent use.
+type ConversationStore interface {
+	// Get returns the state stored under key. ok is false if there is none.
+	Get(key string) (state ConversationState, ok bool, err error)
+	// Set stores the state under key.
+	Set(key string, state ConversationState) error
+	// Delete removes the state stored under key, if any.
+	Delete(key string) error
+}
+
+// ConversationLister is implemented by a ConversationStore able to list
+// its keys, which lets ConversationManager.Sweep delete expired states.
+type ConversationLister interface {
+	Keys() ([]string, error)
+}
+
+// MemoryConversationStore is a ConversationStore keeping states in memory.
+type MemoryConversationStore struct {
+	mu     sync.Mutex
+	states map[string]ConversationState
+}
+
+// NewMemoryConversationStore creates an empty MemoryConversationStore.
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		states: make(map[string]ConversationState),
+	}
+}
+
+// Get returns the state stored under key.
+func (s *MemoryConversationStore) Get(key string) (ConversationState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[key]
+	return state, ok, nil
+}
+
+// Set stores the state under key.
+func (s *MemoryConversationStore) Set(key string, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key] = state
+	return nil
+}
+
+// Delete removes the state stored under key.
+func (s *MemoryConversationStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, key)
+	return nil
+}
+
+// Keys returns the keys of the stored states.
+func (s *MemoryConversationStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return stateKeys(s.states), nil
+}
+
+// FileConversationStore is a ConversationStore keeping states in memory and
+// saving all of them to a JSON file on every change, so that conversations
+// survive restarts.
+type FileConversationStore struct {
+	path string
+
+	mu     sync.Mutex
+	states map[string]ConversationState
+}
+
+// NewFileConversationStore creates a FileConversationStore saving to path.
+// States already saved there are loaded.
+func NewFileConversationStore(path string) (*FileConversationStore, error) {
+	s := &FileConversationStore{
+		path:   path,
+		states: make(map[string]ConversationState),
+	}
+
+	if fileutil.IsFileExist(path) {
+		if err := jsonutil.LoadFile(path, &s.states); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Get returns the state stored under key.
+func (s *FileConversationStore) Get(key string) (ConversationState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[key]
+	return state, ok, nil
+}
+
+// Set stores the state under key and saves the file.
+func (s *FileConversationStore) Set(key string, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key] = state
+	return jsonutil.SaveFile(s.path, s.states)
+}
+
+// Delete removes the state stored under key and saves the file.
+func (s *FileConversationStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.states[key]; !ok {
+		return nil
+	}
+
+	delete(s.states, key)
+	return jsonutil.SaveFile(s.path, s.states)
+}
+
+// Keys returns the keys of the stored states.
+func (s *FileConversationStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return stateKeys(s.states), nil
+}
+
+// stateKeys returns the keys of states.
+func stateKeys(states map[string]ConversationState) []string {
+	keys := make([]string, 0, len(states))
+	for key := range states {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Conversation is the active conversation passed to a step handler.
+//
+// A step handler moves the conversation on with Next or finishes it with
+// End. If it does neither, the conversation stays on the same step, which
+// is useful to ask again after invalid input.
+type Conversation struct {
+	ChatID int64
+	UserID int
+
+	state ConversationState
+	ended bool
+}
+
+// Step returns the current step.
+func (c *Conversation) Step() string {
+	return c.state.Step
+}
+
+// Get returns a value collected earlier in the conversation.
+func (c *Conversation) Get(key string) string {
+	return c.state.Data[key]
+}
+
+// Set stores a value for the later steps.
+func (c *Conversation) Set(key, value string) {
+	if c.state.Data == nil {
+		c.state.Data = make(map[string]string)
+	}
+	c.state.Data[key] = value
+}
+
+// Data returns all the values collected so far.
+func (c *Conversation) Data() map[string]string {
+	return c.state.Data
+}
+
+// Next moves the conversation to step, which handles the next update.
+func (c *Conversation) Next(step string) {
+	c.state.Step = step
+}
+
+// End finishes the conversation and removes its state.
+func (c *Conversation) End() {
+	c.ended = true
+}
+
+// StepHandlerFunc handles an update sent during a step of a conversation.
+type StepHandlerFunc func(bot *BotAPI, update Update, conversation *Conversation)
+
+// ConversationManager runs multi-step dialogs, such as collecting a form
+// over several messages.
+//
+// A conversation belongs to one user in one chat and is started with Start,
+// usually from a command handler. While it is active, the updates from that
+// user in that chat are passed to the handler of the current step instead
+// of the Router's routes. The updates of a conversation are handled one at
+// a time, while different conversations are handled concurrently.
+type ConversationManager struct {
+	// Timeout abandons conversations idle for longer. Zero disables it.
+	Timeout time.Duration
+	// CancelCommand ends the active conversation, without the leading
+	// slash. Empty disables it.
+	CancelCommand string
+	// OnCancel, if set, is called after a conversation was cancelled.
+	OnCancel HandlerFunc
+	// OnTimeout, if set, is called with the first update received after a
+	// conversation timed out. The update is then handled as usual.
+	OnTimeout HandlerFunc
+
+	store ConversationStore
+	steps map[string]StepHandlerFunc
+
+	mu    sync.Mutex
+	locks map[string]*conversationLock
+}
+
+// conversationLock serializes the updates of a conversation.
+type conversationLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewConversationManager creates a ConversationManager keeping its states
+// in store.
+func NewConversationManager(store ConversationStore) *ConversationManager {
+	return &ConversationManager{
+		Timeout:       DefaultConversationTimeout,
+		CancelCommand: DefaultCancelCommand,
+		store:         store,
+		steps:         make(map[string]StepHandlerFunc),
+		locks:         make(map[string]*conversationLock),
+	}
+}
+
+// Step registers the handler of a step.
+func (m *ConversationManager) Step(step string, handler StepHandlerFunc) {
+	m.steps[step] = handler
+}
+
+// Start begins a conversation with the sender of update at step, replacing
+// any active one.
+func (m *ConversationManager) Start(update Update, step string) error {
+	key, ok := conversationKey(update)
+	if !ok {
+		return errors.New(ErrNoSender)
+	}
+
+	return m.store.Set(key, ConversationState{Step: step, UpdatedAt: time.Now()})
+}
+
+// Cancel ends the conversation with the sender of update, if any.
+func (m *ConversationManager) Cancel(update Update) error {
+	key, ok := conversationKey(update)
+	if !ok {
+		return nil
+	}
+
+	return m.store.Delete(key)
+}
+
+// Active returns if the sender of update has an active conversation.
+func (m *ConversationManager) Active(update Update) bool {
+	key, ok := conversationKey(update)
+	if !ok {
+		return false
+	}
+
+	state, ok, err := m.store.Get(key)
+	return err == nil && ok && !m.expired(state)
+}
+
+// Handle passes update to the current step of its conversation. It returns
+// false if there is no active conversation, in which case the update should
+// be handled as usual.
+func (m *ConversationManager) Handle(bot *BotAPI, update Update) bool {
+	key, ok := conversationKey(update)
+	if !ok {
+		return false
+	}
+
+	unlock := m.lock(key)
+
+	state, ok, err := m.store.Get(key)
+	if err != nil {
+		unlock()
+		log.Error("load conversation", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	if !ok {
+		unlock()
+		return false
+	}
+
+	if m.expired(state) {
+		m.delete(key)
+		unlock()
+		if m.OnTimeout != nil {
+			m.OnTimeout(bot, update)
+		}
+		return false
+	}
+
+	if m.isCancel(update) {
+		m.delete(key)
+		unlock()
+		if m.OnCancel != nil {
+			m.OnCancel(bot, update)
+		}
+		return true
+	}
+	defer unlock()
+
+	handler, ok := m.steps[state.Step]
+	if !ok {
+		log.Error("unknown conversation step", zap.String("key", key), zap.String("step", state.Step))
+		m.delete(key)
+		return false
+	}
+
+	// The data is copied, so that the handler doesn't change the map held
+	// by the store while it may be saved.
+	data := make(map[string]string, len(state.Data))
+	for k, v := range state.Data {
+		data[k] = v
+	}
+	state.Data = data
+
+	chat, user := update.FromChat(), update.SentFrom()
+	conversation := &Conversation{ChatID: chat.ID, UserID: user.ID, state: state}
+	handler(bot, update, conversation)
+
+	// A conversation started or cancelled by the handler replaces this
+	// one.
+	if current, ok, err := m.store.Get(key); err != nil || !ok || current.Step != state.Step || !current.UpdatedAt.Equal(state.UpdatedAt) {
+		if err != nil {
+			log.Error("load conversation", zap.String("key", key), zap.Error(err))
+		}
+		return true
+	}
+
+	if conversation.ended {
+		m.delete(key)
+		return true
+	}
+
+	conversation.state.UpdatedAt = time.Now()
+	if err := m.store.Set(key, conversation.state); err != nil {
+		log.Error("save conversation", zap.String("key", key), zap.Error(err))
+	}
+
+	return true
+}
+
+// Sweep deletes the states of expired conversations, so that they don't
+// pile up in the store. OnTimeout is not called for them. It does nothing
+// if the store does not implement ConversationLister.
+func (m *ConversationManager) Sweep() error {
+	lister, ok := m.store.(ConversationLister)
+	if !ok || m.Timeout <= 0 {
+		return nil
+	}
+
+	keys, err := lister.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := m.sweep(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartSweeping calls Sweep every interval in the background until ctx is
+// cancelled.
+func (m *ConversationManager) StartSweeping(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Sweep(); err != nil {
+					log.Error("sweep conversations", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// sweep deletes the state stored under key if it expired.
+func (m *ConversationManager) sweep(key string) error {
+	unlock := m.lock(key)
+	defer unlock()
+
+	state, ok, err := m.store.Get(key)
+	if err != nil || !ok || !m.expired(state) {
+		return err
+	}
+
+	return m.store.Delete(key)
+}
+
+// lock waits until no other update of the conversation stored under key
+// is handled, and returns the function releasing it.
+func (m *ConversationManager) lock(key string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &conversationLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// Middleware returns a Middleware passing updates of active conversations
+// to the ConversationManager instead of the next handler.
+func (m *ConversationManager) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			if m.Handle(bot, update) {
+				return
+			}
+
+			next(bot, update)
+		}
+	}
+}
+
+// expired returns if the conversation was idle for longer than Timeout.
+func (m *ConversationManager) expired(state ConversationState) bool {
+	return m.Timeout > 0 && time.Since(state.UpdatedAt) > m.Timeout
+}
+
+// isCancel returns if update is the cancel command.
+func (m *ConversationManager) isCancel(update Update) bool {
+	if m.CancelCommand == "" || update.Message == nil || !update.Message.IsCommand() {
+		return false
+	}
+
+	return strings.EqualFold(update.Message.Command(), m.CancelCommand)
+}
+
+// delete removes a conversation, logging failures.
+func (m *ConversationManager) delete(key string) {
+	if err := m.store.Delete(key); err != nil {
+		log.Error("delete conversation", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// conversationKey returns the store key of the conversation the update
+// belongs to.
+func conversationKey(update Update) (string, bool) {
+	chat, user := update.FromChat(), update.SentFrom()
+	if chat == nil || user == nil {
+		return "", false
+	}
+
+	return strconv.FormatInt(chat.ID, 10) + ":" + strconv.Itoa(user.ID), true
+}
@@ -0,0 +1,209 @@
+package tgbotapi
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func textUpdate(text string) Update {
+	return Update{
+		Message: &Message{
+			Text: text,
+			Chat: &Chat{ID: 1, Type: "private"},
+			From: &User{ID: 7},
+		},
+	}
+}
+
+func newFormRouter(conversations *ConversationManager, done func(data map[string]string)) *Router {
+	conversations.Step("name", func(bot *BotAPI, update Update, c *Conversation) {
+		c.Set("name", update.Message.Text)
+		c.Next("phone")
+	})
+	conversations.Step("phone", func(bot *BotAPI, update Update, c *Conversation) {
+		c.Set("phone", update.Message.Text)
+		done(c.Data())
+		c.End()
+	})
+
+	r := NewRouter(nil)
+	r.Use(conversations.Middleware())
+	r.Command("form", func(bot *BotAPI, update Update) {
+		conversations.Start(update, "name")
+	})
+
+	return r
+}
+
+func TestConversation(t *testing.T) {
+	conversations := NewConversationManager(NewMemoryConversationStore())
+
+	var result map[string]string
+	r := newFormRouter(conversations, func(data map[string]string) {
+		result = data
+	})
+
+	r.HandleUpdate(commandUpdate("/form", 5))
+	assert.True(t, conversations.Active(textUpdate("")))
+	r.HandleUpdate(textUpdate("Alice"))
+	r.HandleUpdate(textUpdate("+100"))
+
+	assert.Equal(t, map[string]string{"name": "Alice", "phone": "+100"}, result)
+	assert.False(t, conversations.Active(textUpdate("")))
+}
+
+func TestConversationCancelAndTimeout(t *testing.T) {
+	conversations := NewConversationManager(NewMemoryConversationStore())
+
+	var events []string
+	conversations.OnCancel = func(bot *BotAPI, update Update) {
+		events = append(events, "cancel")
+	}
+	conversations.OnTimeout = func(bot *BotAPI, update Update) {
+		events = append(events, "timeout")
+	}
+	r := newFormRouter(conversations, func(data map[string]string) {
+		events = append(events, "done")
+	})
+	r.NotFound(func(bot *BotAPI, update Update) {
+		events = append(events, "notfound")
+	})
+
+	r.HandleUpdate(commandUpdate("/form", 5))
+	r.HandleUpdate(commandUpdate("/cancel", 7))
+	r.HandleUpdate(textUpdate("Alice"))
+
+	conversations.Timeout = time.Millisecond
+	r.HandleUpdate(commandUpdate("/form", 5))
+	time.Sleep(10 * time.Millisecond)
+	r.HandleUpdate(textUpdate("Alice"))
+
+	assert.Equal(t, []string{"cancel", "notfound", "timeout", "notfound"}, events)
+}
+
+func TestConversationConcurrency(t *testing.T) {
+	conversations := NewConversationManager(NewMemoryConversationStore())
+
+	release := make(chan struct{})
+	conversations.Step("slow", func(bot *BotAPI, update Update, c *Conversation) {
+		if update.Message.Chat.ID == 1 {
+			<-release
+		}
+		c.End()
+	})
+	conversations.Step("reentrant", func(bot *BotAPI, update Update, c *Conversation) {
+		if conversations.Active(update) {
+			conversations.Start(update, "slow")
+		}
+	})
+
+	slow, fast := textUpdate("a"), textUpdate("b")
+	fast.Message.Chat = &Chat{ID: 2, Type: "private"}
+	assert.Nil(t, conversations.Start(slow, "slow"))
+	assert.Nil(t, conversations.Start(fast, "reentrant"))
+
+	done := make(chan struct{})
+	go func() {
+		conversations.Handle(nil, slow)
+		close(done)
+	}()
+
+	handled := make(chan bool)
+	go func() {
+		handled <- conversations.Handle(nil, fast)
+	}()
+	select {
+	case ok := <-handled:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("conversation blocked by another chat")
+	}
+
+	close(release)
+	<-done
+	assert.False(t, conversations.Active(slow))
+
+	state, ok, err := conversations.store.Get("2:7")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "slow", state.Step)
+}
+
+func TestConversationData(t *testing.T) {
+	store, err := NewFileConversationStore(filepath.Join(t.TempDir(), "conversations.json"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	conversations := NewConversationManager(store)
+	conversations.Step("name", func(bot *BotAPI, update Update, c *Conversation) {
+		c.Set("name", update.Message.Text)
+		// Other conversations are saved meanwhile.
+		time.Sleep(time.Millisecond)
+		c.Set("chat", strconv.FormatInt(update.Message.Chat.ID, 10))
+	})
+
+	var updates []Update
+	for i := int64(1); i <= 4; i++ {
+		update := textUpdate("Alice")
+		update.Message.Chat = &Chat{ID: i, Type: "private"}
+		key := strconv.FormatInt(i, 10) + ":7"
+		assert.Nil(t, store.Set(key, ConversationState{Step: "name", Data: map[string]string{"name": ""}, UpdatedAt: time.Now()}))
+		updates = append(updates, update)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for _, update := range updates {
+			wg.Add(1)
+			go func(update Update) {
+				defer wg.Done()
+				conversations.Handle(nil, update)
+			}(update)
+		}
+	}
+	wg.Wait()
+
+	state, ok, err := store.Get("3:7")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "Alice", "chat": "3"}, state.Data)
+}
+
+func TestConversationSweep(t *testing.T) {
+	store := NewMemoryConversationStore()
+	conversations := NewConversationManager(store)
+	conversations.Timeout = time.Minute
+
+	assert.Nil(t, store.Set("1:7", ConversationState{Step: "name", UpdatedAt: time.Now().Add(-time.Hour)}))
+	assert.Nil(t, store.Set("1:8", ConversationState{Step: "name", UpdatedAt: time.Now()}))
+
+	assert.Nil(t, conversations.Sweep())
+	keys, err := store.Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1:8"}, keys)
+}
+
+func TestFileConversationStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.json")
+
+	store, err := NewFileConversationStore(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, store.Set("1:7", ConversationState{Step: "phone", Data: map[string]string{"name": "Alice"}}))
+
+	store, err = NewFileConversationStore(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	state, ok, err := store.Get("1:7")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "phone", state.Step)
+	assert.Equal(t, "Alice", state.Data["name"])
+}
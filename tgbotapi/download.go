@@ -0,0 +1,186 @@
+package tgbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// partialSuffix is appended to the path of a download in progress.
+const partialSuffix = ".part"
+
+// DownloadFile streams the content of a file to w.
+//
+// The size of the content is checked against File.FileSize when Telegram
+// reports it. Files of a Bot API server running in local mode are read from
+// the file system.
+func (bot *BotAPI) DownloadFile(ctx context.Context, fileID string, w io.Writer) (File, error) {
+	file, err := bot.GetFileWithContext(ctx, FileConfig{FileID: fileID})
+	if err != nil {
+		return file, err
+	}
+
+	body, _, err := bot.openFile(ctx, file, 0)
+	if err != nil {
+		return file, err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return file, err
+	}
+
+	return file, checkFileSize(file, n)
+}
+
+// DownloadToPath downloads a file to filePath, creating its directory.
+//
+// The content is written to filePath with a ".part" suffix first and moved
+// into place once complete. A partial file left by an interrupted download
+// is resumed with a range request.
+func (bot *BotAPI) DownloadToPath(ctx context.Context, fileID, filePath string) (File, error) {
+	file, err := bot.GetFileWithContext(ctx, FileConfig{FileID: fileID})
+	if err != nil {
+		return file, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return file, err
+	}
+
+	partial := filePath + partialSuffix
+	out, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return file, err
+	}
+	defer out.Close()
+
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return file, err
+	}
+	if file.FileSize > 0 && offset > int64(file.FileSize) {
+		offset = 0
+	}
+
+	if file.FileSize == 0 || offset < int64(file.FileSize) {
+		if offset, err = bot.resume(ctx, file, out, offset); err != nil {
+			return file, err
+		}
+	}
+
+	if err := checkFileSize(file, offset); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return file, err
+	}
+	if err := out.Close(); err != nil {
+		return file, err
+	}
+
+	return file, os.Rename(partial, filePath)
+}
+
+// resume downloads the content of file from offset into out, and returns
+// the size of the complete content.
+func (bot *BotAPI) resume(ctx context.Context, file File, out *os.File, offset int64) (int64, error) {
+	body, restarted, err := bot.openFile(ctx, file, offset)
+	if err != nil {
+		return offset, err
+	}
+	defer body.Close()
+
+	if restarted {
+		offset = 0
+	}
+	if err := out.Truncate(offset); err != nil {
+		return offset, err
+	}
+	if _, err := out.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	n, err := io.Copy(out, body)
+	return offset + n, err
+}
+
+// openFile opens the content of file from offset. restarted is true if the
+// server ignored the offset and sends the whole content.
+func (bot *BotAPI) openFile(ctx context.Context, file File, offset int64) (body io.ReadCloser, restarted bool, err error) {
+	if file.IsLocal() {
+		f, err := os.Open(file.FilePath)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, false, err
+		}
+
+		return readerWithContext{ctx: ctx, ReadCloser: f}, false, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bot.fileURL(file), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := bot.Client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, offset > 0, nil
+	case http.StatusPartialContent:
+		return resp.Body, false, nil
+	default:
+		defer resp.Body.Close()
+		return nil, false, downloadError(resp)
+	}
+}
+
+// checkFileSize returns an error if n differs from the size Telegram
+// reported for file.
+func checkFileSize(file File, n int64) error {
+	if file.FileSize > 0 && n != int64(file.FileSize) {
+		return fmt.Errorf("%s: got %d bytes, want %d", ErrFileSizeMismatch, n, file.FileSize)
+	}
+
+	return nil
+}
+
+// downloadError returns the error of a failed download response.
+func downloadError(resp *http.Response) error {
+	var apiResp APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err == nil && apiResp.ErrorCode != 0 {
+		return newError(apiResp)
+	}
+
+	return errors.New("download failed: " + resp.Status)
+}
+
+// readerWithContext stops reading once ctx is done.
+type readerWithContext struct {
+	ctx context.Context
+	io.ReadCloser
+}
+
+func (r readerWithContext) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.ReadCloser.Read(p)
+}
@@ -0,0 +1,104 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an error returned by the Telegram API.
+//
+// Use errors.As to get it from errors returned by BotAPI methods, or one of
+// the Is functions to check for common failures.
+type Error struct {
+	Code    int
+	Message string
+	ResponseParameters
+}
+
+// newError creates an Error from an unsuccessful APIResponse.
+func newError(resp APIResponse) *Error {
+	err := &Error{
+		Code:    resp.ErrorCode,
+		Message: resp.Description,
+	}
+	if resp.Parameters != nil {
+		err.ResponseParameters = *resp.Parameters
+	}
+
+	return err
+}
+
+// Error returns the description of the error given by Telegram.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// RetryAfterDuration returns how long to wait before repeating the request,
+// or zero if Telegram did not ask to wait.
+func (e *Error) RetryAfterDuration() time.Duration {
+	return time.Duration(e.RetryAfter) * time.Second
+}
+
+// asError returns the Error wrapped by err, if any.
+func asError(err error) (*Error, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	return nil, false
+}
+
+// hasDescription returns if err is an Error with the code and a
+// description containing text.
+func hasDescription(err error, code int, text string) bool {
+	apiErr, ok := asError(err)
+	return ok && apiErr.Code == code && strings.Contains(strings.ToLower(apiErr.Message), text)
+}
+
+// IsForbidden returns if the bot is not allowed to perform the request,
+// such as when the bot was blocked by the user or kicked from the chat.
+func IsForbidden(err error) bool {
+	apiErr, ok := asError(err)
+	return ok && apiErr.Code == http.StatusForbidden
+}
+
+// IsUserDeactivated returns if the request failed because the user
+// deleted their account.
+func IsUserDeactivated(err error) bool {
+	return hasDescription(err, http.StatusForbidden, "user is deactivated")
+}
+
+// IsTooManyRequests returns if the request hit flood control. The delay
+// Telegram asks for is available from Error.RetryAfterDuration.
+func IsTooManyRequests(err error) bool {
+	apiErr, ok := asError(err)
+	return ok && apiErr.Code == http.StatusTooManyRequests
+}
+
+// IsTemporary returns if a request failed for a reason that may go away
+// by itself, such as a network failure, flood control or a server error,
+// so that sending it again later may succeed.
+func IsTemporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	apiErr, ok := asError(err)
+	return !ok || apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+}
+
+// IsChatNotFound returns if the chat of the request does not exist or is
+// unknown to the bot.
+func IsChatNotFound(err error) bool {
+	return hasDescription(err, http.StatusBadRequest, "chat not found")
+}
+
+// IsMessageNotModified returns if an edit was rejected because it would
+// not change the message.
+func IsMessageNotModified(err error) bool {
+	return hasDescription(err, http.StatusBadRequest, "message is not modified")
+}
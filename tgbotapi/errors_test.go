@@ -0,0 +1,40 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	err := fmt.Errorf("send: %w", newError(APIResponse{
+		ErrorCode:   429,
+		Description: "Too Many Requests: retry after 5",
+		Parameters:  &ResponseParameters{RetryAfter: 5},
+	}))
+
+	assert.True(t, IsTooManyRequests(err))
+	assert.False(t, IsForbidden(err))
+
+	var apiErr *Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 429, apiErr.Code)
+		assert.Equal(t, 5*time.Second, apiErr.RetryAfterDuration())
+	}
+
+	assert.True(t, IsForbidden(newError(APIResponse{ErrorCode: 403, Description: "Forbidden: bot was blocked by the user"})))
+	assert.True(t, IsChatNotFound(newError(APIResponse{ErrorCode: 400, Description: "Bad Request: chat not found"})))
+	assert.True(t, IsMessageNotModified(newError(APIResponse{ErrorCode: 400, Description: "Bad Request: message is not modified"})))
+	assert.False(t, IsChatNotFound(errors.New("Bad Request: chat not found")))
+
+	assert.True(t, IsTemporary(err))
+	assert.True(t, IsTemporary(errors.New("connection reset by peer")))
+	assert.True(t, IsTemporary(newError(APIResponse{ErrorCode: 502, Description: "Bad Gateway"})))
+	assert.False(t, IsTemporary(newError(APIResponse{ErrorCode: 400, Description: "Bad Request: message to edit not found"})))
+	assert.False(t, IsTemporary(context.Canceled))
+	assert.False(t, IsTemporary(nil))
+}
@@ -0,0 +1,263 @@
+package tgbotapi
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	htmlEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+	)
+	markdownEscaper = strings.NewReplacer(
+		"_", `\_`,
+		"*", `\*`,
+		"`", "\\`",
+		"[", `\[`,
+	)
+	markdownV2Escaper = strings.NewReplacer(
+		`\`, `\\`,
+		"_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+		"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`,
+		"=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+	)
+	markdownV2CodeEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		"`", "\\`",
+	)
+	markdownV2URLEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		")", `\)`,
+	)
+)
+
+// EscapeText escapes text so that it is shown as is in a message sent with
+// parseMode. Text for an unknown parse mode is returned unchanged.
+func EscapeText(parseMode string, text string) string {
+	switch parseMode {
+	case ModeHTML:
+		return htmlEscaper.Replace(text)
+	case ModeMarkdown:
+		return markdownEscaper.Replace(text)
+	case ModeMarkdownV2:
+		return markdownV2Escaper.Replace(text)
+	default:
+		return text
+	}
+}
+
+// UTF16Len returns the length of s in UTF-16 code units, the unit Telegram
+// uses for entity offsets and text limits.
+func UTF16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+
+	return n
+}
+
+// utf16RuneLen returns the number of UTF-16 code units encoding r.
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+
+	return 1
+}
+
+// Formatter builds a formatted message text out of plain and formatted
+// segments, escaping them for its parse mode.
+//
+// A Formatter created with an empty parse mode produces plain text along
+// with MessageEntity values instead, which never need escaping. Legacy
+// Markdown has no underline, strikethrough or spoiler, so those segments
+// are written as plain text in ModeMarkdown.
+type Formatter struct {
+	parseMode string
+
+	text     strings.Builder
+	length   int
+	entities []MessageEntity
+}
+
+// NewFormatter creates a Formatter for parseMode, which is one of
+// ModeMarkdown, ModeMarkdownV2, ModeHTML or empty for entities.
+func NewFormatter(parseMode string) *Formatter {
+	return &Formatter{
+		parseMode: parseMode,
+	}
+}
+
+// Text appends plain text.
+func (f *Formatter) Text(text string) *Formatter {
+	f.write(EscapeText(f.parseMode, text), text)
+
+	return f
+}
+
+// Bold appends bold text.
+func (f *Formatter) Bold(text string) *Formatter {
+	return f.wrap(MessageEntity{Type: "bold"}, text, "<b>", "</b>", "*", "*")
+}
+
+// Italic appends italic text.
+func (f *Formatter) Italic(text string) *Formatter {
+	return f.wrap(MessageEntity{Type: "italic"}, text, "<i>", "</i>", "_", "_")
+}
+
+// Underline appends underlined text.
+func (f *Formatter) Underline(text string) *Formatter {
+	return f.wrap(MessageEntity{Type: "underline"}, text, "<u>", "</u>", "__", "")
+}
+
+// Strikethrough appends strikethrough text.
+func (f *Formatter) Strikethrough(text string) *Formatter {
+	return f.wrap(MessageEntity{Type: "strikethrough"}, text, "<s>", "</s>", "~", "")
+}
+
+// Spoiler appends text hidden until the user taps it.
+func (f *Formatter) Spoiler(text string) *Formatter {
+	return f.wrap(MessageEntity{Type: "spoiler"}, text, "<tg-spoiler>", "</tg-spoiler>", "||", "")
+}
+
+// Code appends inline monospace text.
+//
+// Legacy Markdown can't escape inside code, so backticks are removed from
+// text in ModeMarkdown.
+func (f *Formatter) Code(text string) *Formatter {
+	switch f.parseMode {
+	case ModeHTML:
+		f.write("<code>"+htmlEscaper.Replace(text)+"</code>", text)
+	case ModeMarkdown:
+		text := strings.Replace(text, "`", "", -1)
+		f.write("`"+text+"`", text)
+	case ModeMarkdownV2:
+		f.write("`"+markdownV2CodeEscaper.Replace(text)+"`", text)
+	default:
+		f.entity(MessageEntity{Type: "code"}, text)
+	}
+
+	return f
+}
+
+// Pre appends a block of preformatted code, highlighted as language if it
+// is not empty.
+func (f *Formatter) Pre(text, language string) *Formatter {
+	switch f.parseMode {
+	case ModeHTML:
+		if language != "" {
+			f.write(`<pre><code class="language-`+htmlEscaper.Replace(language)+`">`+htmlEscaper.Replace(text)+"</code></pre>", text)
+		} else {
+			f.write("<pre>"+htmlEscaper.Replace(text)+"</pre>", text)
+		}
+	case ModeMarkdown:
+		text := strings.Replace(text, "`", "", -1)
+		f.write("```"+language+"\n"+text+"```", text)
+	case ModeMarkdownV2:
+		f.write("```"+markdownV2CodeEscaper.Replace(language)+"\n"+markdownV2CodeEscaper.Replace(text)+"```", text)
+	default:
+		f.entity(MessageEntity{Type: "pre", Language: language}, text)
+	}
+
+	return f
+}
+
+// Link appends text linking to url.
+func (f *Formatter) Link(text, url string) *Formatter {
+	switch f.parseMode {
+	case ModeHTML:
+		f.write(`<a href="`+htmlEscaper.Replace(url)+`">`+htmlEscaper.Replace(text)+"</a>", text)
+	case ModeMarkdown:
+		text := strings.Replace(text, "]", "", -1)
+		f.write("["+text+"]("+url+")", text)
+	case ModeMarkdownV2:
+		f.write("["+markdownV2Escaper.Replace(text)+"]("+markdownV2URLEscaper.Replace(url)+")", text)
+	default:
+		f.entity(MessageEntity{Type: "text_link", URL: url}, text)
+	}
+
+	return f
+}
+
+// Mention appends text mentioning the user, which works for users without
+// a username.
+func (f *Formatter) Mention(text string, userID int) *Formatter {
+	if f.parseMode == "" {
+		f.entity(MessageEntity{Type: "text_mention", User: &User{ID: userID}}, text)
+		return f
+	}
+
+	return f.Link(text, "tg://user?id="+strconv.Itoa(userID))
+}
+
+// String returns the formatted text.
+func (f *Formatter) String() string {
+	return f.text.String()
+}
+
+// ParseMode returns the parse mode of the Formatter.
+func (f *Formatter) ParseMode() string {
+	return f.parseMode
+}
+
+// Entities returns the entities of the text. It is empty unless the
+// Formatter was created without a parse mode.
+func (f *Formatter) Entities() []MessageEntity {
+	return f.entities
+}
+
+// Len returns the length of the visible text in UTF-16 code units.
+func (f *Formatter) Len() int {
+	return f.length
+}
+
+// Message creates a MessageConfig sending the formatted text to chatID.
+func (f *Formatter) Message(chatID int64) MessageConfig {
+	msg := NewMessage(chatID, f.String())
+	msg.ParseMode = f.parseMode
+	msg.Entities = f.entities
+
+	return msg
+}
+
+// wrap appends text between the markers of the parse mode. Legacy Markdown
+// closes and reopens the entity around markers found in text.
+func (f *Formatter) wrap(entity MessageEntity, text, htmlOpen, htmlClose, markdownV2, markdown string) *Formatter {
+	switch f.parseMode {
+	case ModeHTML:
+		f.write(htmlOpen+htmlEscaper.Replace(text)+htmlClose, text)
+	case ModeMarkdown:
+		if markdown == "" {
+			return f.Text(text)
+		}
+		f.write(markdown+strings.Replace(text, markdown, markdown+`\`+markdown+markdown, -1)+markdown, text)
+	case ModeMarkdownV2:
+		f.write(markdownV2+markdownV2Escaper.Replace(text)+markdownV2, text)
+	default:
+		f.entity(entity, text)
+	}
+
+	return f
+}
+
+// entity appends text covered by entity.
+func (f *Formatter) entity(entity MessageEntity, text string) {
+	entity.Offset = f.length
+	entity.Length = UTF16Len(text)
+	if entity.Length > 0 {
+		f.entities = append(f.entities, entity)
+	}
+
+	f.write(text, text)
+}
+
+// write appends raw text, which shows as visible.
+func (f *Formatter) write(raw, visible string) {
+	f.text.WriteString(raw)
+	f.length += UTF16Len(visible)
+}
@@ -0,0 +1,64 @@
+package tgbotapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, "a &lt;b&gt; &amp; &quot;c&quot;", EscapeText(ModeHTML, `a <b> & "c"`))
+	assert.Equal(t, `snake\_case \*x\*`, EscapeText(ModeMarkdown, "snake_case *x*"))
+	assert.Equal(t, `1\.5 \(x\-y\)\! \\`, EscapeText(ModeMarkdownV2, `1.5 (x-y)! \`))
+	assert.Equal(t, "a_b", EscapeText("", "a_b"))
+}
+
+func TestFormatterModes(t *testing.T) {
+	build := func(mode string) string {
+		return NewFormatter(mode).
+			Text("Hi ").
+			Bold("a_b").
+			Text(" ").
+			Code("x`y").
+			Text(" ").
+			Link("site", "https://example.com/a_(b)").
+			String()
+	}
+
+	assert.Equal(t, `Hi <b>a_b</b> <code>x`+"`"+`y</code> <a href="https://example.com/a_(b)">site</a>`, build(ModeHTML))
+	assert.Equal(t, "Hi *a\\_b* `x\\`y` [site](https://example.com/a_(b\\))", build(ModeMarkdownV2))
+	assert.Equal(t, "Hi *a_b* `xy` [site](https://example.com/a_(b))", build(ModeMarkdown))
+}
+
+func TestFormatterLen(t *testing.T) {
+	f := NewFormatter(ModeMarkdown).
+		Code("x`y").
+		Link("[a]", "https://example.com").
+		Pre("``z", "go")
+
+	assert.Equal(t, "`xy`[[a](https://example.com)```go\nz```", f.String())
+	assert.Equal(t, 5, f.Len())
+}
+
+func TestFormatterEntities(t *testing.T) {
+	f := NewFormatter("").
+		Text("😀 ").
+		Bold("bold").
+		Text(" ").
+		Pre("fmt.Println()", "go").
+		Mention("Bob", 42)
+
+	assert.Equal(t, "😀 bold fmt.Println()Bob", f.String())
+	assert.Equal(t, 24, f.Len())
+	assert.Equal(t, []MessageEntity{
+		{Type: "bold", Offset: 3, Length: 4},
+		{Type: "pre", Offset: 8, Length: 13, Language: "go"},
+		{Type: "text_mention", Offset: 21, Length: 3, User: &User{ID: 42}},
+	}, f.Entities())
+
+	msg := f.Message(1)
+	assert.Equal(t, "", msg.ParseMode)
+	v, err := msg.values()
+	assert.Nil(t, err)
+	assert.Contains(t, v.Get("entities"), `"language":"go"`)
+}
@@ -253,6 +253,80 @@ func NewVoiceShare(chatID int64, fileID string) VoiceConfig {
 	}
 }
 
+// NewMediaGroup creates a new media group to send as an album.
+//
+// chatID is where to send it, media holds InputMedia items.
+func NewMediaGroup(chatID int64, media []interface{}) MediaGroupConfig {
+	return MediaGroupConfig{
+		BaseChat: BaseChat{ChatID: chatID},
+		Media:    media,
+	}
+}
+
+// NewInputMediaPhoto creates a photo for a media group from a file ID
+// or URL.
+func NewInputMediaPhoto(media string) InputMediaPhoto {
+	return InputMediaPhoto{
+		BaseInputMedia: BaseInputMedia{Type: "photo", Media: media},
+	}
+}
+
+// NewInputMediaPhotoUpload creates a photo for a media group from a string
+// path to the file, FileReader, or FileBytes.
+func NewInputMediaPhotoUpload(file interface{}) InputMediaPhoto {
+	return InputMediaPhoto{
+		BaseInputMedia: BaseInputMedia{Type: "photo", File: file},
+	}
+}
+
+// NewInputMediaVideo creates a video for a media group from a file ID
+// or URL.
+func NewInputMediaVideo(media string) InputMediaVideo {
+	return InputMediaVideo{
+		BaseInputMedia: BaseInputMedia{Type: "video", Media: media},
+	}
+}
+
+// NewInputMediaVideoUpload creates a video for a media group from a string
+// path to the file, FileReader, or FileBytes.
+func NewInputMediaVideoUpload(file interface{}) InputMediaVideo {
+	return InputMediaVideo{
+		BaseInputMedia: BaseInputMedia{Type: "video", File: file},
+	}
+}
+
+// NewInputMediaDocument creates a document for a media group from a file
+// ID or URL.
+func NewInputMediaDocument(media string) InputMediaDocument {
+	return InputMediaDocument{
+		BaseInputMedia: BaseInputMedia{Type: "document", Media: media},
+	}
+}
+
+// NewInputMediaDocumentUpload creates a document for a media group from
+// a string path to the file, FileReader, or FileBytes.
+func NewInputMediaDocumentUpload(file interface{}) InputMediaDocument {
+	return InputMediaDocument{
+		BaseInputMedia: BaseInputMedia{Type: "document", File: file},
+	}
+}
+
+// NewInputMediaAudio creates an audio file for a media group from a file
+// ID or URL.
+func NewInputMediaAudio(media string) InputMediaAudio {
+	return InputMediaAudio{
+		BaseInputMedia: BaseInputMedia{Type: "audio", Media: media},
+	}
+}
+
+// NewInputMediaAudioUpload creates an audio file for a media group from
+// a string path to the file, FileReader, or FileBytes.
+func NewInputMediaAudioUpload(file interface{}) InputMediaAudio {
+	return InputMediaAudio{
+		BaseInputMedia: BaseInputMedia{Type: "audio", File: file},
+	}
+}
+
 // NewContact allows you to send a shared contact.
 func NewContact(chatID int64, phoneNumber, firstName string) ContactConfig {
 	return ContactConfig{
@@ -290,6 +364,199 @@ func NewVenue(chatID int64, title, address string, latitude, longitude float64)
 	}
 }
 
+// NewPoll creates a new anonymous poll.
+//
+// chatID is where to send it, question and options are the poll content.
+func NewPoll(chatID int64, question string, options ...string) SendPollConfig {
+	return SendPollConfig{
+		BaseChat:    BaseChat{ChatID: chatID},
+		Question:    question,
+		Options:     options,
+		IsAnonymous: true,
+		Type:        PollTypeRegular,
+	}
+}
+
+// NewQuiz creates a new anonymous quiz.
+//
+// chatID is where to send it, correctOptionID is the index of the right
+// answer in options.
+func NewQuiz(chatID int64, question string, correctOptionID int, options ...string) SendPollConfig {
+	return SendPollConfig{
+		BaseChat:        BaseChat{ChatID: chatID},
+		Question:        question,
+		Options:         options,
+		IsAnonymous:     true,
+		Type:            PollTypeQuiz,
+		CorrectOptionID: correctOptionID,
+	}
+}
+
+// NewStopPoll allows you to stop a poll sent by the bot.
+func NewStopPoll(chatID int64, messageID int) StopPollConfig {
+	return StopPollConfig{
+		BaseEdit: BaseEdit{
+			ChatID:    chatID,
+			MessageID: messageID,
+		},
+	}
+}
+
+// NewDice creates a new dice with the default emoji.
+func NewDice(chatID int64) DiceConfig {
+	return DiceConfig{
+		BaseChat: BaseChat{ChatID: chatID},
+	}
+}
+
+// NewDiceWithEmoji creates a new dice with one of the dice emoji
+// constants, such as DartsEmoji.
+func NewDiceWithEmoji(chatID int64, emoji string) DiceConfig {
+	return DiceConfig{
+		BaseChat: BaseChat{ChatID: chatID},
+		Emoji:    emoji,
+	}
+}
+
+// NewCreateForumTopic creates a new topic in a forum supergroup.
+func NewCreateForumTopic(chatID int64, name string) CreateForumTopicConfig {
+	return CreateForumTopicConfig{
+		BaseForum: BaseForum{ChatID: chatID},
+		Name:      name,
+	}
+}
+
+// NewEditForumTopic renames a topic.
+func NewEditForumTopic(chatID int64, messageThreadID int, name string) EditForumTopicConfig {
+	return EditForumTopicConfig{
+		BaseForumTopic: BaseForumTopic{
+			BaseForum:       BaseForum{ChatID: chatID},
+			MessageThreadID: messageThreadID,
+		},
+		Name: name,
+	}
+}
+
+// NewCloseForumTopic closes a topic.
+func NewCloseForumTopic(chatID int64, messageThreadID int) CloseForumTopicConfig {
+	return CloseForumTopicConfig{
+		BaseForumTopic: BaseForumTopic{
+			BaseForum:       BaseForum{ChatID: chatID},
+			MessageThreadID: messageThreadID,
+		},
+	}
+}
+
+// NewReopenForumTopic reopens a closed topic.
+func NewReopenForumTopic(chatID int64, messageThreadID int) ReopenForumTopicConfig {
+	return ReopenForumTopicConfig{
+		BaseForumTopic: BaseForumTopic{
+			BaseForum:       BaseForum{ChatID: chatID},
+			MessageThreadID: messageThreadID,
+		},
+	}
+}
+
+// NewDeleteForumTopic deletes a topic along with its messages.
+func NewDeleteForumTopic(chatID int64, messageThreadID int) DeleteForumTopicConfig {
+	return DeleteForumTopicConfig{
+		BaseForumTopic: BaseForumTopic{
+			BaseForum:       BaseForum{ChatID: chatID},
+			MessageThreadID: messageThreadID,
+		},
+	}
+}
+
+// NewBotCommand creates a new command for the command menu, without the
+// leading slash.
+func NewBotCommand(command, description string) BotCommand {
+	return BotCommand{
+		Command:     command,
+		Description: description,
+	}
+}
+
+// NewSetMyCommands sets the command list of the default scope.
+func NewSetMyCommands(commands ...BotCommand) SetMyCommandsConfig {
+	return SetMyCommandsConfig{
+		Commands: commands,
+	}
+}
+
+// NewSetMyCommandsWithScope sets the command list of scope for users with
+// languageCode, or users of any language if it is empty.
+func NewSetMyCommandsWithScope(scope BotCommandScope, languageCode string, commands ...BotCommand) SetMyCommandsConfig {
+	return SetMyCommandsConfig{
+		BaseBotCommands: BaseBotCommands{Scope: &scope, LanguageCode: languageCode},
+		Commands:        commands,
+	}
+}
+
+// NewGetMyCommands gets the command list of the default scope.
+func NewGetMyCommands() GetMyCommandsConfig {
+	return GetMyCommandsConfig{}
+}
+
+// NewGetMyCommandsWithScope gets the command list of scope for users with
+// languageCode.
+func NewGetMyCommandsWithScope(scope BotCommandScope, languageCode string) GetMyCommandsConfig {
+	return GetMyCommandsConfig{
+		BaseBotCommands: BaseBotCommands{Scope: &scope, LanguageCode: languageCode},
+	}
+}
+
+// NewDeleteMyCommands deletes the command list of the default scope.
+func NewDeleteMyCommands() DeleteMyCommandsConfig {
+	return DeleteMyCommandsConfig{}
+}
+
+// NewDeleteMyCommandsWithScope deletes the command list of scope for users
+// with languageCode.
+func NewDeleteMyCommandsWithScope(scope BotCommandScope, languageCode string) DeleteMyCommandsConfig {
+	return DeleteMyCommandsConfig{
+		BaseBotCommands: BaseBotCommands{Scope: &scope, LanguageCode: languageCode},
+	}
+}
+
+// NewBotCommandScopeDefault is the scope used when no narrower scope
+// applies.
+func NewBotCommandScopeDefault() BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeDefault}
+}
+
+// NewBotCommandScopeAllPrivateChats covers all private chats.
+func NewBotCommandScopeAllPrivateChats() BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeAllPrivateChats}
+}
+
+// NewBotCommandScopeAllGroupChats covers all group and supergroup chats.
+func NewBotCommandScopeAllGroupChats() BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeAllGroupChats}
+}
+
+// NewBotCommandScopeAllChatAdministrators covers all group and supergroup
+// chat administrators.
+func NewBotCommandScopeAllChatAdministrators() BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeAllChatAdministrators}
+}
+
+// NewBotCommandScopeChat covers a specific chat.
+func NewBotCommandScopeChat(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeChat, ChatID: chatID}
+}
+
+// NewBotCommandScopeChatAdministrators covers the administrators of a
+// specific group or supergroup chat.
+func NewBotCommandScopeChatAdministrators(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeChatAdministrators, ChatID: chatID}
+}
+
+// NewBotCommandScopeChatMember covers a specific member of a group or
+// supergroup chat.
+func NewBotCommandScopeChatMember(chatID int64, userID int) BotCommandScope {
+	return BotCommandScope{Type: BotCommandScopeTypeChatMember, ChatID: chatID, UserID: userID}
+}
+
 // NewChatAction sets a chat action.
 // Actions last for 5 seconds, or until your next action.
 //
@@ -474,6 +741,77 @@ func NewInlineQueryResultLocation(id, title string, latitude, longitude float64)
 	}
 }
 
+// NewInlineQueryResultCachedPhoto creates a new inline query photo stored
+// on the Telegram servers.
+func NewInlineQueryResultCachedPhoto(id, fileID string) InlineQueryResultCachedPhoto {
+	return InlineQueryResultCachedPhoto{
+		Type:   "photo",
+		ID:     id,
+		FileID: fileID,
+	}
+}
+
+// NewInlineQueryResultCachedSticker creates a new inline query sticker
+// stored on the Telegram servers.
+func NewInlineQueryResultCachedSticker(id, fileID string) InlineQueryResultCachedSticker {
+	return InlineQueryResultCachedSticker{
+		Type:   "sticker",
+		ID:     id,
+		FileID: fileID,
+	}
+}
+
+// NewInlineQueryResultCachedDocument creates a new inline query document
+// stored on the Telegram servers.
+func NewInlineQueryResultCachedDocument(id, title, fileID string) InlineQueryResultCachedDocument {
+	return InlineQueryResultCachedDocument{
+		Type:   "document",
+		ID:     id,
+		Title:  title,
+		FileID: fileID,
+	}
+}
+
+// NewInlineQueryResultContact creates a new inline query contact.
+func NewInlineQueryResultContact(id, phoneNumber, firstName string) InlineQueryResultContact {
+	return InlineQueryResultContact{
+		Type:        "contact",
+		ID:          id,
+		PhoneNumber: phoneNumber,
+		FirstName:   firstName,
+	}
+}
+
+// NewInlineQueryResultVenue creates a new inline query venue.
+func NewInlineQueryResultVenue(id, title, address string, latitude, longitude float64) InlineQueryResultVenue {
+	return InlineQueryResultVenue{
+		Type:      "venue",
+		ID:        id,
+		Title:     title,
+		Address:   address,
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+}
+
+// NewInlineQueryResultsButton creates a button above inline query results
+// opening a private chat with the bot, sending /start with startParameter.
+func NewInlineQueryResultsButton(text, startParameter string) InlineQueryResultsButton {
+	return InlineQueryResultsButton{
+		Text:           text,
+		StartParameter: startParameter,
+	}
+}
+
+// NewInlineQueryResultsButtonWebApp creates a button above inline query
+// results opening a Web App.
+func NewInlineQueryResultsButtonWebApp(text, url string) InlineQueryResultsButton {
+	return InlineQueryResultsButton{
+		Text:   text,
+		WebApp: &WebAppInfo{URL: url},
+	}
+}
+
 // NewEditMessageText allows you to edit the text of a message.
 func NewEditMessageText(chatID int64, messageID int, text string) EditMessageTextConfig {
 	return EditMessageTextConfig{
@@ -508,6 +846,39 @@ func NewEditMessageReplyMarkup(chatID int64, messageID int, replyMarkup InlineKe
 	}
 }
 
+// NewEditMessageMedia allows you to replace the media of a message.
+func NewEditMessageMedia(chatID int64, messageID int, media interface{}) EditMessageMediaConfig {
+	return EditMessageMediaConfig{
+		BaseEdit: BaseEdit{
+			ChatID:    chatID,
+			MessageID: messageID,
+		},
+		Media: media,
+	}
+}
+
+// NewEditMessageLiveLocation allows you to move a live location.
+func NewEditMessageLiveLocation(chatID int64, messageID int, latitude, longitude float64) EditMessageLiveLocationConfig {
+	return EditMessageLiveLocationConfig{
+		BaseEdit: BaseEdit{
+			ChatID:    chatID,
+			MessageID: messageID,
+		},
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+}
+
+// NewStopMessageLiveLocation allows you to stop updating a live location.
+func NewStopMessageLiveLocation(chatID int64, messageID int) StopMessageLiveLocationConfig {
+	return StopMessageLiveLocationConfig{
+		BaseEdit: BaseEdit{
+			ChatID:    chatID,
+			MessageID: messageID,
+		},
+	}
+}
+
 // NewHideKeyboard hides the keyboard, with the option for being selective
 // or hiding for everyone.
 //func NewHideKeyboard(selective bool) ReplyKeyboardHide {
@@ -640,6 +1011,34 @@ func NewCallbackWithAlert(id, text string) CallbackConfig {
 	}
 }
 
+// NewBanChatMember creates a request to ban a user from a chat forever.
+func NewBanChatMember(chatID int64, userID int) BanChatMemberConfig {
+	return BanChatMemberConfig{
+		ChatMemberConfig: ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+	}
+}
+
+// NewSetChatPermissions creates a request to set the default permissions
+// of the members of a chat.
+func NewSetChatPermissions(chatID int64, permissions ChatPermissions) SetChatPermissionsConfig {
+	return SetChatPermissionsConfig{
+		ChatID:      chatID,
+		Permissions: permissions,
+	}
+}
+
+// NewDeleteMessages creates a request to delete several messages in a
+// chat.
+func NewDeleteMessages(chatID int64, messageIDs ...int) DeleteMessagesConfig {
+	return DeleteMessagesConfig{
+		ChatID:     chatID,
+		MessageIDs: messageIDs,
+	}
+}
+
 // NewInvoice created a new Invoice request to the user.
 func NewInvoice(chatID int64, title, description, payload, providerToken, startParameter, currency string, prices *[]LabeledPrice) InvoiceConfig {
 	return InvoiceConfig{
@@ -652,3 +1051,41 @@ func NewInvoice(chatID int64, title, description, payload, providerToken, startP
 		Currency:       currency,
 		Prices:         prices}
 }
+
+// NewStarsInvoice creates a new Invoice request to the user for a payment
+// in Telegram Stars.
+func NewStarsInvoice(chatID int64, title, description, payload string, amount int) InvoiceConfig {
+	return InvoiceConfig{
+		BaseChat:    BaseChat{ChatID: chatID},
+		Title:       title,
+		Description: description,
+		Payload:     payload,
+		Currency:    CurrencyStars,
+		Prices:      &[]LabeledPrice{{Label: title, Amount: amount}}}
+}
+
+// NewInvoiceLink creates a new request for a link to an invoice.
+func NewInvoiceLink(title, description, payload, providerToken, currency string, prices *[]LabeledPrice) CreateInvoiceLinkConfig {
+	return CreateInvoiceLinkConfig{
+		Title:         title,
+		Description:   description,
+		Payload:       payload,
+		ProviderToken: providerToken,
+		Currency:      currency,
+		Prices:        prices}
+}
+
+// NewStarsInvoiceLink creates a new request for a link to an invoice for a
+// payment in Telegram Stars.
+func NewStarsInvoiceLink(title, description, payload string, amount int) CreateInvoiceLinkConfig {
+	return NewStarsInvoice(0, title, description, payload, amount).invoiceLink()
+}
+
+// NewRefundStarPayment creates a new request to refund a payment in
+// Telegram Stars.
+func NewRefundStarPayment(userID int, telegramPaymentChargeID string) RefundStarPaymentConfig {
+	return RefundStarPaymentConfig{
+		UserID:                  userID,
+		TelegramPaymentChargeID: telegramPaymentChargeID,
+	}
+}
@@ -0,0 +1,168 @@
+package tgbotapi
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaxInlineQueryResults is the number of results Telegram accepts in one
+// answer to an inline query.
+const MaxInlineQueryResults = 50
+
+// DefaultInlineCacheTTL is how long an InlineHandler keeps the results of
+// a query.
+const DefaultInlineCacheTTL = 5 * time.Minute
+
+// InlineResultsFunc returns every result for an inline query, in order.
+// InlineHandler sends them a page at a time.
+type InlineResultsFunc func(query InlineQuery) ([]interface{}, error)
+
+// InlineHandler answers inline queries with pages of results.
+//
+// Results are computed once per query string and cached for CacheTTL, so
+// that the following pages, requested with the offset of the previous
+// answer, are sliced from the same list. Results of a personal handler are
+// cached per user.
+type InlineHandler struct {
+	// PageSize is the number of results per answer, at most
+	// MaxInlineQueryResults.
+	PageSize int
+	// CacheTTL is how long results are cached, 0 disables the cache.
+	CacheTTL time.Duration
+	// CacheTime is how long Telegram may cache answers, in seconds.
+	CacheTime  int
+	IsPersonal bool
+	Button     *InlineQueryResultsButton
+
+	results InlineResultsFunc
+
+	mu    sync.Mutex
+	cache map[string]inlineCacheEntry
+}
+
+// inlineCacheEntry is the cached results of a query.
+type inlineCacheEntry struct {
+	results []interface{}
+	expires time.Time
+}
+
+// NewInlineHandler creates an InlineHandler answering with the results
+// returned by results.
+func NewInlineHandler(results InlineResultsFunc) *InlineHandler {
+	return &InlineHandler{
+		PageSize: MaxInlineQueryResults,
+		CacheTTL: DefaultInlineCacheTTL,
+		results:  results,
+		cache:    make(map[string]inlineCacheEntry),
+	}
+}
+
+// Answer returns the answer to a query, holding the page of results at
+// the offset of the query. An offset not sent by the handler, such as one
+// past the last result, is answered with no results and no next offset,
+// so that the client stops asking for more.
+func (h *InlineHandler) Answer(query InlineQuery) (InlineConfig, error) {
+	results, err := h.load(query)
+	if err != nil {
+		return InlineConfig{}, err
+	}
+
+	offset := 0
+	if query.Offset != "" {
+		offset, err = strconv.Atoi(query.Offset)
+		if err != nil || offset < 0 || offset > len(results) {
+			offset = len(results)
+		}
+	}
+
+	end := offset + h.pageSize()
+	if end > len(results) {
+		end = len(results)
+	}
+
+	config := InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results[offset:end],
+		CacheTime:     h.CacheTime,
+		IsPersonal:    h.IsPersonal,
+		Button:        h.Button,
+	}
+	if end < len(results) {
+		config.NextOffset = strconv.Itoa(end)
+	}
+
+	return config, nil
+}
+
+// Handle answers the inline query of an update. Failures are logged.
+func (h *InlineHandler) Handle(bot *BotAPI, update Update) {
+	if update.InlineQuery == nil {
+		return
+	}
+
+	config, err := h.Answer(*update.InlineQuery)
+	if err != nil {
+		log.Error("inline query results", zap.String("query", update.InlineQuery.Query), zap.Error(err))
+		return
+	}
+
+	if _, err := bot.AnswerInlineQuery(config); err != nil {
+		log.Error("answer inline query", zap.String("query", update.InlineQuery.Query), zap.Error(err))
+	}
+}
+
+// Register adds a route answering every inline query to the router.
+func (h *InlineHandler) Register(r *Router) {
+	r.InlineQuery(h.Handle)
+}
+
+// pageSize returns the number of results per answer.
+func (h *InlineHandler) pageSize() int {
+	if h.PageSize < 1 || h.PageSize > MaxInlineQueryResults {
+		return MaxInlineQueryResults
+	}
+
+	return h.PageSize
+}
+
+// load returns the results of a query from the cache, or computes them.
+func (h *InlineHandler) load(query InlineQuery) ([]interface{}, error) {
+	if h.CacheTTL <= 0 {
+		return h.results(query)
+	}
+
+	key := query.Query
+	if h.IsPersonal && query.From != nil {
+		key = strconv.Itoa(query.From.ID) + ":" + key
+	}
+
+	now := time.Now()
+
+	h.mu.Lock()
+	entry, ok := h.cache[key]
+	h.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.results, nil
+	}
+
+	results, err := h.results(query)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for k, e := range h.cache {
+		if !now.Before(e.expires) {
+			delete(h.cache, k)
+		}
+	}
+	h.cache[key] = inlineCacheEntry{results: results, expires: now.Add(h.CacheTTL)}
+
+	return results, nil
+}
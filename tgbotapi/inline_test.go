@@ -0,0 +1,58 @@
+package tgbotapi
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlineHandler(t *testing.T) {
+	var calls int
+	h := NewInlineHandler(func(query InlineQuery) ([]interface{}, error) {
+		calls++
+		results := make([]interface{}, 120)
+		for i := range results {
+			results[i] = NewInlineQueryResultArticle(strconv.Itoa(i), query.Query, query.Query)
+		}
+		return results, nil
+	})
+	h.PageSize = 100
+
+	query := InlineQuery{ID: "q", Query: "cats", From: &User{ID: 7}}
+	config, err := h.Answer(query)
+	assert.Nil(t, err)
+	assert.Len(t, config.Results, MaxInlineQueryResults)
+	assert.Equal(t, "50", config.NextOffset)
+
+	query.Offset = config.NextOffset
+	config, err = h.Answer(query)
+	assert.Nil(t, err)
+	assert.Equal(t, "50", config.Results[0].(InlineQueryResultArticle).ID)
+	assert.Equal(t, "100", config.NextOffset)
+
+	query.Offset = "100"
+	config, err = h.Answer(query)
+	assert.Nil(t, err)
+	assert.Len(t, config.Results, 20)
+	assert.Empty(t, config.NextOffset)
+	assert.Equal(t, 1, calls)
+
+	query.Offset = "bogus"
+	config, err = h.Answer(query)
+	assert.Nil(t, err)
+	assert.NotNil(t, config.Results)
+	assert.Empty(t, config.Results)
+	assert.Empty(t, config.NextOffset)
+
+	query.Offset = "500"
+	config, err = h.Answer(query)
+	assert.Nil(t, err)
+	assert.Empty(t, config.Results)
+	assert.Empty(t, config.NextOffset)
+
+	h.IsPersonal = true
+	_, err = h.Answer(InlineQuery{ID: "q", Query: "cats", From: &User{ID: 8}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+}
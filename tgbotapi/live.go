@@ -0,0 +1,237 @@
+package tgbotapi
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultLiveMessageInterval is the least time between two edits of a
+// LiveMessage.
+const DefaultLiveMessageInterval = 3 * time.Second
+
+// LiveMessage is a message edited repeatedly to show progress, such as a
+// "processing…" message.
+//
+// SetText and SetReplyMarkup only record the new content. It is sent in
+// the background at most once every Interval, so that rapid changes are
+// coalesced into one edit and don't hit flood control. Edits that would
+// not change the message are skipped. An edit failing in the background
+// for a temporary reason, as reported by IsTemporary, is sent again after
+// Interval with the content set meanwhile; other failures are kept for
+// Err and only the next change is sent. Call Close once done to send the
+// final content; changes made afterwards are ignored.
+type LiveMessage struct {
+	Interval              time.Duration
+	ParseMode             string
+	DisableWebPagePreview bool
+
+	bot    *BotAPI
+	target BaseEdit
+
+	// editMu is held while an edit is sent, so that edits are sent in
+	// order.
+	editMu sync.Mutex
+
+	mu         sync.Mutex
+	text       string
+	markup     *InlineKeyboardMarkup
+	sentText   string
+	sentMarkup *InlineKeyboardMarkup
+	lastEdit   time.Time
+	timer      *time.Timer
+	err        error
+	closed     bool
+}
+
+// NewLiveMessage creates a LiveMessage editing a message in a chat.
+func NewLiveMessage(bot *BotAPI, chatID int64, messageID int) *LiveMessage {
+	return newLiveMessage(bot, BaseEdit{ChatID: chatID, MessageID: messageID})
+}
+
+// NewInlineLiveMessage creates a LiveMessage editing a message sent via
+// the bot in inline mode.
+func NewInlineLiveMessage(bot *BotAPI, inlineMessageID string) *LiveMessage {
+	return newLiveMessage(bot, BaseEdit{InlineMessageID: inlineMessageID})
+}
+
+// StartLiveMessage sends a message and returns a LiveMessage editing it.
+// The first edit is sent no sooner than Interval after the message.
+func StartLiveMessage(bot *BotAPI, config MessageConfig) (*LiveMessage, error) {
+	message, err := bot.Send(config)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewLiveMessage(bot, message.Chat.ID, message.MessageID)
+	m.ParseMode = config.ParseMode
+	m.DisableWebPagePreview = config.DisableWebPagePreview
+	m.text = config.Text
+	m.sentText = config.Text
+	switch markup := config.ReplyMarkup.(type) {
+	case InlineKeyboardMarkup:
+		m.markup = &markup
+	case *InlineKeyboardMarkup:
+		m.markup = markup
+	}
+	m.sentMarkup = m.markup
+	m.lastEdit = time.Now()
+
+	return m, nil
+}
+
+// newLiveMessage creates a LiveMessage editing the target message.
+func newLiveMessage(bot *BotAPI, target BaseEdit) *LiveMessage {
+	return &LiveMessage{
+		Interval: DefaultLiveMessageInterval,
+		bot:      bot,
+		target:   target,
+	}
+}
+
+// SetText changes the text of the message.
+func (m *LiveMessage) SetText(text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.text = text
+	m.schedule()
+}
+
+// SetReplyMarkup changes the inline keyboard of the message. nil removes
+// it.
+func (m *LiveMessage) SetReplyMarkup(markup *InlineKeyboardMarkup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.markup = markup
+	m.schedule()
+}
+
+// Err returns the error of the last edit sent in the background, if it
+// failed.
+func (m *LiveMessage) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// Close sends the content not sent yet, waiting for the end of the current
+// Interval if needed, and returns the error of the edit.
+func (m *LiveMessage) Close(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	changed := m.changed()
+	wait := time.Until(m.lastEdit.Add(m.Interval))
+	m.mu.Unlock()
+
+	if changed && wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return m.edit(ctx)
+}
+
+// schedule starts a timer sending the content once the Interval since the
+// last edit has passed, unless the message is closed. The caller must
+// hold mu.
+func (m *LiveMessage) schedule() {
+	if m.closed || m.timer != nil || !m.changed() {
+		return
+	}
+
+	wait := time.Until(m.lastEdit.Add(m.Interval))
+	if wait < 0 {
+		wait = 0
+	}
+
+	m.timer = time.AfterFunc(wait, func() {
+		m.mu.Lock()
+		m.timer = nil
+		m.mu.Unlock()
+
+		if err := m.edit(context.Background()); err != nil {
+			log.Error("edit live message", zap.Error(err))
+		}
+	})
+}
+
+// changed returns if the content differs from the content sent. The
+// caller must hold mu.
+func (m *LiveMessage) changed() bool {
+	return m.text != m.sentText || !reflect.DeepEqual(m.markup, m.sentMarkup)
+}
+
+// edit sends the content if it changed.
+func (m *LiveMessage) edit(ctx context.Context) error {
+	m.editMu.Lock()
+	defer m.editMu.Unlock()
+
+	m.mu.Lock()
+	if !m.changed() {
+		m.mu.Unlock()
+		return nil
+	}
+	text, markup := m.text, m.markup
+	textChanged := text != m.sentText
+	m.mu.Unlock()
+
+	target := m.target
+	target.ReplyMarkup = markup
+
+	var err error
+	if textChanged {
+		_, err = DoWithContext(ctx, m.bot, EditMessageTextConfig{
+			BaseEdit:              target,
+			Text:                  text,
+			ParseMode:             m.ParseMode,
+			DisableWebPagePreview: m.DisableWebPagePreview,
+		})
+	} else {
+		if target.ReplyMarkup == nil {
+			target.ReplyMarkup = &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{}}
+		}
+		_, err = DoWithContext(ctx, m.bot, EditMessageReplyMarkupConfig{BaseEdit: target})
+	}
+	if IsMessageNotModified(err) {
+		err = nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastEdit = time.Now()
+	m.err = err
+	if err == nil {
+		m.sentText, m.sentMarkup = text, markup
+	}
+
+	// Content set while the edit was sent, or not sent because the edit
+	// failed for a temporary reason, is sent by a new timer.
+	if err == nil || IsTemporary(err) {
+		m.schedule()
+	}
+
+	return err
+}
@@ -0,0 +1,417 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultManagerMaxIdleConnsPerHost is the number of idle connections to
+// the Bot API kept by the transport of a BotManager, shared by all bots.
+const DefaultManagerMaxIdleConnsPerHost = 100
+
+// DefaultManagerRetryDelay is how long a BotManager first waits before
+// trying again to get a bot or set its webhook. The delay doubles after
+// each failure, up to maxManagerRetryDelay.
+const DefaultManagerRetryDelay = time.Second
+
+// maxManagerRetryDelay is the longest a BotManager waits before trying
+// again to get a bot or set its webhook.
+const maxManagerRetryDelay = time.Minute
+
+// BotManager runs many bots in one process.
+//
+// Bots share one http.Client, so that they reuse connections to the Bot
+// API. Adding a bot makes no request: Self is loaded in the background
+// once the manager is started. Each bot receives updates either with long
+// polling, or through the manager's single webhook endpoint, which routes
+// requests to the bot by the last element of the path of their webhook
+// URL, or else by their secret token. Bots failing to load or to set their
+// webhook are tried again with a growing delay, and only receive updates
+// once loaded.
+type BotManager struct {
+	// Client is used by the bots added afterwards.
+	Client *http.Client
+	// APIEndpoint is the endpoint of the bots added afterwards, formatted
+	// like APIEndpoint.
+	APIEndpoint string
+	// UpdateConfig is used by the bots receiving updates with polling.
+	UpdateConfig UpdateConfig
+	// RetryDelay is the first delay before trying again to get a bot or
+	// set its webhook.
+	RetryDelay time.Duration
+
+	mu      sync.RWMutex
+	bots    map[string]*ManagedBot
+	paths   map[string]*ManagedBot
+	secrets map[string]*ManagedBot
+	ctx     context.Context
+	wg      sync.WaitGroup
+}
+
+// ManagedBot is a bot run by a BotManager.
+type ManagedBot struct {
+	Bot *BotAPI
+
+	handler HandlerFunc
+	webhook *WebhookConfig
+	server  *WebhookServer
+	path    string
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu     sync.Mutex
+	health BotHealth
+}
+
+// BotHealth is the state of a ManagedBot.
+type BotHealth struct {
+	// ID is the ID of the bot, taken from its token.
+	ID       string
+	UserName string
+	Webhook  bool
+	Running  bool
+	// Loaded is whether the bot was loaded and its webhook set, so that it
+	// receives updates.
+	Loaded bool
+	// LastUpdate is when the last update was received.
+	LastUpdate time.Time
+	Updates    int
+	// Errors counts the failed requests to get the bot, set its webhook
+	// or get updates.
+	Errors      int
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// NewBotManager creates an empty BotManager.
+func NewBotManager() *BotManager {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultManagerMaxIdleConnsPerHost
+
+	config := NewUpdate(0)
+	config.Timeout = 60
+
+	return &BotManager{
+		Client:       &http.Client{Transport: transport},
+		APIEndpoint:  APIEndpoint,
+		UpdateConfig: config,
+		RetryDelay:   DefaultManagerRetryDelay,
+		bots:         make(map[string]*ManagedBot),
+		paths:        make(map[string]*ManagedBot),
+		secrets:      make(map[string]*ManagedBot),
+	}
+}
+
+// Add adds a bot receiving updates with polling and passing them to
+// handler, one at a time.
+func (m *BotManager) Add(token string, handler HandlerFunc) (*ManagedBot, error) {
+	return m.add(token, nil, handler)
+}
+
+// AddWebhook adds a bot receiving updates through the manager's webhook
+// endpoint and passing them to handler, one at a time. The webhook is set
+// with config once the manager is started.
+//
+// The last element of the path of config.URL and config.SecretToken must
+// not be used by another bot, and at least one of them must be set.
+func (m *BotManager) AddWebhook(token string, config WebhookConfig, handler HandlerFunc) (*ManagedBot, error) {
+	return m.add(token, &config, handler)
+}
+
+// add adds a bot, receiving updates with a webhook if it is not nil.
+func (m *BotManager) add(token string, webhook *WebhookConfig, handler HandlerFunc) (*ManagedBot, error) {
+	bot := newBotAPI(token, m.APIEndpoint, m.Client)
+	b := &ManagedBot{
+		Bot:     bot,
+		handler: handler,
+		webhook: webhook,
+		done:    make(chan struct{}),
+		health: BotHealth{
+			ID:      strings.SplitN(token, ":", 2)[0],
+			Webhook: webhook != nil,
+		},
+	}
+	if webhook != nil {
+		b.server = NewWebhookServer(bot, *webhook)
+		if webhook.URL != nil {
+			b.path = path.Base(webhook.URL.Path)
+		}
+		if b.path == "/" || b.path == "." {
+			b.path = ""
+		}
+	}
+
+	if webhook != nil && b.path == "" && webhook.SecretToken == "" {
+		return nil, errors.New(ErrWebhookUnroutable)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bots[token] != nil || m.paths[b.path] != nil || (webhook != nil && m.secrets[webhook.SecretToken] != nil) {
+		return nil, errors.New(ErrBotAlreadyAdded)
+	}
+
+	m.bots[token] = b
+	if b.path != "" {
+		m.paths[b.path] = b
+	}
+	if webhook != nil && webhook.SecretToken != "" {
+		m.secrets[webhook.SecretToken] = b
+	}
+
+	if m.ctx != nil {
+		m.start(b)
+	}
+
+	return b, nil
+}
+
+// Bot returns the bot with a token, or nil.
+func (m *BotManager) Bot(token string) *ManagedBot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.bots[token]
+}
+
+// Remove stops a bot and removes it from the manager, waiting for its
+// current update to be handled. Its webhook is left set.
+//
+// Remove must not be called from the handler of the bot it removes, which
+// would wait for itself: call it in a new goroutine there.
+func (m *BotManager) Remove(token string) {
+	m.mu.Lock()
+	b := m.bots[token]
+	if b == nil {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.bots, token)
+	if b.path != "" {
+		delete(m.paths, b.path)
+	}
+	if b.webhook != nil && b.webhook.SecretToken != "" {
+		delete(m.secrets, b.webhook.SecretToken)
+	}
+	m.mu.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		<-b.done
+	}
+}
+
+// Start starts the bots in the background until ctx is cancelled. Bots
+// added afterwards are started right away. A manager can only be started
+// once: later calls are ignored.
+func (m *BotManager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx != nil {
+		return
+	}
+
+	m.ctx = ctx
+	for _, b := range m.bots {
+		m.start(b)
+	}
+}
+
+// Wait waits for the bots to stop once the context passed to Start is
+// cancelled.
+func (m *BotManager) Wait() {
+	m.wg.Wait()
+}
+
+// Health returns the state of every bot, ordered by ID.
+func (m *BotManager) Health() []BotHealth {
+	m.mu.RLock()
+	health := make([]BotHealth, 0, len(m.bots))
+	for _, b := range m.bots {
+		health = append(health, b.Health())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].ID < health[j].ID
+	})
+
+	return health
+}
+
+// ServeHTTP passes a request sent by Telegram to the webhook of its bot.
+// Requests for no bot are rejected with 404, and requests for a bot not
+// loaded with 503.
+func (m *BotManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	b := m.paths[path.Base(r.URL.Path)]
+	if b == nil {
+		if secret := r.Header.Get(WebhookSecretHeader); secret != "" {
+			b = m.secrets[secret]
+		}
+	}
+	m.mu.RUnlock()
+
+	if b == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !b.Health().Loaded {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	b.server.ServeHTTP(w, r)
+}
+
+// start runs a bot in the background. The caller must hold mu.
+func (m *BotManager) start(b *ManagedBot) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	b.cancel = cancel
+
+	b.mu.Lock()
+	b.health.Running = true
+	b.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		b.run(ctx, m.UpdateConfig, m.RetryDelay)
+	}()
+}
+
+// Health returns the state of the bot.
+func (b *ManagedBot) Health() BotHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.health
+}
+
+// run loads the bot and handles its updates until ctx is cancelled.
+func (b *ManagedBot) run(ctx context.Context, config UpdateConfig, retryDelay time.Duration) {
+	defer func() {
+		b.mu.Lock()
+		b.health.Running = false
+		b.health.Loaded = false
+		b.mu.Unlock()
+
+		close(b.done)
+	}()
+
+	// The webhook is closed even if the bot never loads, so that requests
+	// waiting for it are released.
+	if b.webhook != nil {
+		go func() {
+			<-ctx.Done()
+			b.server.Close()
+		}()
+	}
+
+	loaded := b.retry(ctx, retryDelay, "get bot", func() error {
+		self, err := b.Bot.GetMeWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		b.Bot.Self = self
+
+		b.mu.Lock()
+		b.health.UserName = self.UserName
+		b.mu.Unlock()
+
+		return nil
+	})
+	if !loaded {
+		return
+	}
+
+	var updates UpdatesChannel
+	if b.webhook != nil {
+		set := b.retry(ctx, retryDelay, "set webhook", func() error {
+			_, err := b.Bot.SetWebhookWithContext(ctx, *b.webhook)
+			return err
+		})
+		if !set {
+			return
+		}
+
+		updates = b.server.Updates()
+	} else {
+		p := newPoller(b.Bot, config)
+		p.onError = func(err error) {
+			b.fail("get updates", err)
+		}
+		go p.run(ctx)
+		updates = p.Updates()
+	}
+
+	b.mu.Lock()
+	b.health.Loaded = true
+	b.mu.Unlock()
+
+	for update := range updates {
+		b.mu.Lock()
+		b.health.LastUpdate = time.Now()
+		b.health.Updates++
+		b.mu.Unlock()
+
+		if b.handler != nil {
+			b.handler(b.Bot, update)
+		}
+	}
+}
+
+// retry calls f, trying again after a growing delay until it succeeds. It
+// returns false if ctx was cancelled first.
+func (b *ManagedBot) retry(ctx context.Context, delay time.Duration, action string, f func() error) bool {
+	if delay <= 0 {
+		delay = DefaultManagerRetryDelay
+	}
+
+	for {
+		err := f()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		b.fail(action, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > maxManagerRetryDelay {
+			delay = maxManagerRetryDelay
+		}
+	}
+}
+
+// fail records a failed request of the bot.
+func (b *ManagedBot) fail(action string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	log.Error("managed bot "+action, zap.String("bot_id", b.health.ID), zap.Error(err))
+
+	b.health.Errors++
+	b.health.LastError = err.Error()
+	b.health.LastErrorAt = time.Now()
+}
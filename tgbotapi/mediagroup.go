@@ -0,0 +1,138 @@
+package tgbotapi
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Limits of the number of items of a MediaGroupConfig.
+const (
+	MinMediaGroupSize = 2
+	MaxMediaGroupSize = 10
+)
+
+// DefaultMediaGroupWait is how long a MediaGroupCollector waits for more
+// messages of an album before handling it.
+const DefaultMediaGroupWait = time.Second
+
+// MediaGroupHandlerFunc handles all the messages of an album at once.
+type MediaGroupHandlerFunc func(bot *BotAPI, messages []Message)
+
+// MediaGroupCollector gathers the messages of an album, which Telegram
+// delivers as separate updates sharing a MediaGroupID.
+//
+// An album is handled once no new message of it arrived for Wait. Its
+// messages are ordered by MessageID. It is safe for concurrent use.
+//
+// Albums are handled in the background, after the middleware chain of
+// their updates returned: updates acknowledged to a poller are considered
+// handled even though their album may still be waiting, and panics of the
+// handler are recovered and logged by the collector itself.
+type MediaGroupCollector struct {
+	Wait time.Duration
+
+	handler MediaGroupHandlerFunc
+
+	mu     sync.Mutex
+	groups map[string]*mediaGroup
+}
+
+// mediaGroup holds the messages of an album received so far.
+type mediaGroup struct {
+	messages []Message
+	timer    *time.Timer
+	// gen counts the messages added, so that a timer replaced by a later
+	// message doesn't flush the album.
+	gen int
+}
+
+// NewMediaGroupCollector creates a MediaGroupCollector calling handler for
+// every complete album.
+func NewMediaGroupCollector(handler MediaGroupHandlerFunc) *MediaGroupCollector {
+	return &MediaGroupCollector{
+		Wait:    DefaultMediaGroupWait,
+		handler: handler,
+		groups:  make(map[string]*mediaGroup),
+	}
+}
+
+// Add collects a message. It returns false if the message is not part of
+// an album, in which case it should be handled as usual.
+func (c *MediaGroupCollector) Add(bot *BotAPI, message Message) bool {
+	if message.MediaGroupID == "" {
+		return false
+	}
+
+	key := message.MediaGroupID
+	if message.Chat != nil {
+		key = strconv.FormatInt(message.Chat.ID, 10) + ":" + key
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, ok := c.groups[key]
+	if !ok {
+		group = &mediaGroup{}
+		c.groups[key] = group
+	} else {
+		group.timer.Stop()
+	}
+
+	group.messages = append(group.messages, message)
+	group.gen++
+	gen := group.gen
+	group.timer = time.AfterFunc(c.Wait, func() {
+		c.flush(bot, key, gen)
+	})
+
+	return true
+}
+
+// Middleware returns a Middleware collecting album messages, so that they
+// are passed to the collector's handler instead of the next handler.
+func (c *MediaGroupCollector) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			if update.Message != nil && c.Add(bot, *update.Message) {
+				return
+			}
+
+			next(bot, update)
+		}
+	}
+}
+
+// flush handles the album stored under key, unless a message was added
+// to it after generation gen.
+func (c *MediaGroupCollector) flush(bot *BotAPI, key string, gen int) {
+	c.mu.Lock()
+	group, ok := c.groups[key]
+	if !ok || group.gen != gen {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.groups, key)
+	c.mu.Unlock()
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("media group handler panicked",
+				zap.String("media_group_id", group.messages[0].MediaGroupID),
+				zap.String("panic", fmt.Sprint(err)),
+				zap.ByteString("stack", debug.Stack()))
+		}
+	}()
+
+	sort.Slice(group.messages, func(i, j int) bool {
+		return group.messages[i].MessageID < group.messages[j].MessageID
+	})
+
+	c.handler(bot, group.messages)
+}
@@ -0,0 +1,74 @@
+package tgbotapi
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaGroupCollector(t *testing.T) {
+	var mu sync.Mutex
+	var albums [][]Message
+	var handled time.Time
+	c := NewMediaGroupCollector(func(bot *BotAPI, messages []Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		albums = append(albums, messages)
+		handled = time.Now()
+	})
+	c.Wait = 50 * time.Millisecond
+
+	assert.False(t, c.Add(nil, Message{MessageID: 1, Chat: &Chat{ID: 1}}))
+
+	for _, id := range []int{4, 2, 3} {
+		assert.True(t, c.Add(nil, Message{MessageID: id, Chat: &Chat{ID: 1}, MediaGroupID: "album"}))
+		time.Sleep(20 * time.Millisecond)
+	}
+	last := time.Now()
+	assert.True(t, c.Add(nil, Message{MessageID: 5, Chat: &Chat{ID: 2}, MediaGroupID: "album"}))
+
+	mu.Lock()
+	assert.Empty(t, albums)
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(albums) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, handled.Sub(last) >= c.Wait)
+	for _, album := range albums {
+		if album[0].Chat.ID == 1 {
+			assert.Equal(t, []int{2, 3, 4}, []int{album[0].MessageID, album[1].MessageID, album[2].MessageID})
+		} else {
+			assert.Len(t, album, 1)
+		}
+	}
+}
+
+func TestMediaGroupCollectorPanic(t *testing.T) {
+	handled := make(chan string, 2)
+	c := NewMediaGroupCollector(func(bot *BotAPI, messages []Message) {
+		handled <- messages[0].MediaGroupID
+		if messages[0].MediaGroupID == "boom" {
+			panic("boom")
+		}
+	})
+	c.Wait = 10 * time.Millisecond
+
+	c.Add(nil, Message{MessageID: 1, MediaGroupID: "boom"})
+	assert.Equal(t, "boom", <-handled)
+
+	c.Add(nil, Message{MessageID: 2, MediaGroupID: "album"})
+	select {
+	case id := <-handled:
+		assert.Equal(t, "album", id)
+	case <-time.After(time.Second):
+		t.Fatal("album not handled")
+	}
+}
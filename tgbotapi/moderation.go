@@ -0,0 +1,343 @@
+package tgbotapi
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaxDeleteMessages is the number of messages Telegram deletes in one
+// deleteMessages request.
+const MaxDeleteMessages = 100
+
+// DefaultRecentMessages is the number of messages RecentMessages keeps per
+// user and chat.
+const DefaultRecentMessages = 50
+
+// DefaultAdminCacheTTL is how long an AdminCache keeps the administrators
+// of a chat.
+const DefaultAdminCacheTTL = 10 * time.Minute
+
+// chatUserKey identifies a user in a chat.
+type chatUserKey struct {
+	chatID int64
+	userID int
+}
+
+// MuteChatMember stops a user from sending messages to a supergroup for d.
+// A d of 0, less than 30 seconds or more than 366 days mutes forever.
+func (bot *BotAPI) MuteChatMember(config ChatMemberConfig, d time.Duration) (bool, error) {
+	return Do(bot, restrictChatMember(config, false, d))
+}
+
+// UnmuteChatMember lifts the restrictions of a user in a supergroup.
+func (bot *BotAPI) UnmuteChatMember(config ChatMemberConfig) (bool, error) {
+	return Do(bot, restrictChatMember(config, true, 0))
+}
+
+// BanChatMemberFor bans a user from a chat for d. A d of 0, less than 30
+// seconds or more than 366 days bans forever.
+func (bot *BotAPI) BanChatMemberFor(config ChatMemberConfig, d time.Duration) (bool, error) {
+	return bot.BanChatMember(BanChatMemberConfig{ChatMemberConfig: config, UntilDate: untilDate(d)})
+}
+
+// restrictChatMember returns a config granting or denying every
+// permission to a user for d.
+func restrictChatMember(config ChatMemberConfig, allow bool, d time.Duration) RestrictChatMemberConfig {
+	return RestrictChatMemberConfig{
+		ChatMemberConfig: config,
+		UntilDate:        untilDate(d),
+		Permissions: &ChatPermissions{
+			CanSendMessages:       allow,
+			CanSendAudios:         allow,
+			CanSendDocuments:      allow,
+			CanSendPhotos:         allow,
+			CanSendVideos:         allow,
+			CanSendVideoNotes:     allow,
+			CanSendVoiceNotes:     allow,
+			CanSendPolls:          allow,
+			CanSendOtherMessages:  allow,
+			CanAddWebPagePreviews: allow,
+			CanChangeInfo:         allow,
+			CanInviteUsers:        allow,
+			CanPinMessages:        allow,
+			CanManageTopics:       allow,
+		},
+	}
+}
+
+// untilDate returns the Unix time d from now, or 0 for a d of 0.
+func untilDate(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Now().Add(d).Unix()
+}
+
+// RecentMessages remembers the last messages of every user in every chat,
+// so that they can be deleted at once, such as when banning a spammer.
+//
+// The Bot API has no way to list the messages of a chat, so only the
+// messages recorded by the Middleware are known.
+type RecentMessages struct {
+	// Limit is the number of messages kept per user and chat.
+	Limit int
+
+	mu       sync.Mutex
+	messages map[chatUserKey][]int
+}
+
+// NewRecentMessages creates an empty RecentMessages.
+func NewRecentMessages() *RecentMessages {
+	return &RecentMessages{
+		Limit:    DefaultRecentMessages,
+		messages: make(map[chatUserKey][]int),
+	}
+}
+
+// Record remembers a message.
+func (r *RecentMessages) Record(message *Message) {
+	if message == nil || message.Chat == nil || message.From == nil {
+		return
+	}
+
+	key := chatUserKey{chatID: message.Chat.ID, userID: message.From.ID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := append(r.messages[key], message.MessageID)
+	if r.Limit > 0 && len(ids) > r.Limit {
+		ids = ids[len(ids)-r.Limit:]
+	}
+	r.messages[key] = ids
+}
+
+// Middleware returns a Middleware recording every new message.
+func (r *RecentMessages) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			r.Record(update.Message)
+			next(bot, update)
+		}
+	}
+}
+
+// IDs returns the recorded messages of a user in a chat, oldest first.
+func (r *RecentMessages) IDs(chatID int64, userID int) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]int(nil), r.messages[chatUserKey{chatID: chatID, userID: userID}]...)
+}
+
+// Delete deletes the recorded messages of a user in a chat and forgets
+// them. Messages recorded meanwhile are kept, and so are the messages left
+// when a request fails.
+func (r *RecentMessages) Delete(bot *BotAPI, chatID int64, userID int) error {
+	key := chatUserKey{chatID: chatID, userID: userID}
+	ids := r.IDs(chatID, userID)
+
+	for start := 0; start < len(ids); start += MaxDeleteMessages {
+		end := start + MaxDeleteMessages
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		if _, err := bot.DeleteMessages(NewDeleteMessages(chatID, ids[start:end]...)); err != nil {
+			return err
+		}
+
+		r.forget(key, ids[start:end])
+	}
+
+	return nil
+}
+
+// forget removes deleted messages of a user in a chat.
+func (r *RecentMessages) forget(key chatUserKey, deleted []int) {
+	done := make(map[int]bool, len(deleted))
+	for _, id := range deleted {
+		done[id] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int
+	for _, id := range r.messages[key] {
+		if !done[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		delete(r.messages, key)
+	} else {
+		r.messages[key] = ids
+	}
+}
+
+// FloodDetector detects users sending more than Limit messages to a chat
+// within Window.
+type FloodDetector struct {
+	Limit  int
+	Window time.Duration
+
+	mu        sync.Mutex
+	hits      map[chatUserKey][]time.Time
+	lastSweep time.Time
+}
+
+// NewFloodDetector creates a FloodDetector allowing limit messages per
+// user and chat within window.
+func NewFloodDetector(limit int, window time.Duration) *FloodDetector {
+	return &FloodDetector{
+		Limit:  limit,
+		Window: window,
+		hits:   make(map[chatUserKey][]time.Time),
+	}
+}
+
+// Hit records a message of a user to a chat, and returns if the user is
+// flooding the chat.
+func (f *FloodDetector) Hit(chatID int64, userID int) bool {
+	now := time.Now()
+	since := now.Add(-f.Window)
+	key := chatUserKey{chatID: chatID, userID: userID}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Sub(f.lastSweep) > f.Window {
+		for k, hits := range f.hits {
+			if hits[len(hits)-1].Before(since) {
+				delete(f.hits, k)
+			}
+		}
+		f.lastSweep = now
+	}
+
+	hits := f.hits[key]
+	i := 0
+	for i < len(hits) && hits[i].Before(since) {
+		i++
+	}
+	hits = append(hits[i:], now)
+	f.hits[key] = hits
+
+	return len(hits) > f.Limit
+}
+
+// Middleware returns a Middleware passing the messages of users flooding a
+// chat to onFlood instead of the next handler. A nil onFlood drops them.
+func (f *FloodDetector) Middleware(onFlood HandlerFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			message := update.Message
+			if message == nil || message.Chat == nil || message.From == nil || !f.Hit(message.Chat.ID, message.From.ID) {
+				next(bot, update)
+				return
+			}
+
+			if onFlood != nil {
+				onFlood(bot, update)
+			}
+		}
+	}
+}
+
+// AdminCache caches the administrators of chats, to authorize admin-only
+// commands without asking Telegram on every update.
+type AdminCache struct {
+	TTL time.Duration
+
+	bot *BotAPI
+
+	mu    sync.Mutex
+	chats map[int64]adminCacheEntry
+}
+
+// adminCacheEntry is the cached administrators of a chat.
+type adminCacheEntry struct {
+	admins  []ChatMember
+	expires time.Time
+}
+
+// NewAdminCache creates an empty AdminCache for the bot.
+func NewAdminCache(bot *BotAPI) *AdminCache {
+	return &AdminCache{
+		TTL:   DefaultAdminCacheTTL,
+		bot:   bot,
+		chats: make(map[int64]adminCacheEntry),
+	}
+}
+
+// Admins returns the administrators of a chat.
+func (c *AdminCache) Admins(chatID int64) ([]ChatMember, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.chats[chatID]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.admins, nil
+	}
+
+	admins, err := c.bot.GetChatAdministrators(ChatConfig{ChatID: chatID})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.chats[chatID] = adminCacheEntry{admins: admins, expires: now.Add(c.TTL)}
+	c.mu.Unlock()
+
+	return admins, nil
+}
+
+// IsAdmin returns if a user is the creator or an administrator of a chat.
+func (c *AdminCache) IsAdmin(chatID int64, userID int) (bool, error) {
+	admins, err := c.Admins(chatID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, admin := range admins {
+		if admin.User != nil && admin.User.ID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Invalidate forgets the administrators of a chat, such as after
+// promoting a user.
+func (c *AdminCache) Invalidate(chatID int64) {
+	c.mu.Lock()
+	delete(c.chats, chatID)
+	c.mu.Unlock()
+}
+
+// Middleware returns a Middleware only letting through updates sent by
+// administrators of their chat. Wrap admin-only handlers with it using
+// Chain.
+func (c *AdminCache) Middleware() Middleware {
+	return AuthMiddleware(func(update Update) bool {
+		user, chat := update.SentFrom(), update.FromChat()
+		if user == nil || chat == nil {
+			return false
+		}
+
+		admin, err := c.IsAdmin(chat.ID, user.ID)
+		if err != nil {
+			log.Error("get chat administrators", zap.Int64("chat_id", chat.ID), zap.Error(err))
+		}
+
+		return admin
+	})
+}
@@ -0,0 +1,47 @@
+package tgbotapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloodDetector(t *testing.T) {
+	f := NewFloodDetector(3, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		assert.False(t, f.Hit(1, 7))
+	}
+	assert.True(t, f.Hit(1, 7))
+	assert.False(t, f.Hit(1, 8))
+	assert.False(t, f.Hit(2, 7))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, f.Hit(1, 7))
+
+	f = NewFloodDetector(3, time.Minute)
+	var flooded int
+	handler := Chain(func(bot *BotAPI, update Update) {}, f.Middleware(func(bot *BotAPI, update Update) {
+		flooded++
+	}))
+	for i := 0; i < 5; i++ {
+		handler(nil, commandUpdate("/start", 6))
+	}
+	assert.Equal(t, 2, flooded)
+}
+
+func TestRecentMessages(t *testing.T) {
+	r := NewRecentMessages()
+	r.Limit = 3
+
+	for i := 1; i <= 5; i++ {
+		r.Record(&Message{MessageID: i, Chat: &Chat{ID: 1}, From: &User{ID: 7}})
+	}
+	r.Record(&Message{MessageID: 6, Chat: &Chat{ID: 1}, From: &User{ID: 8}})
+	r.Record(&Message{MessageID: 7, Chat: &Chat{ID: 1}})
+
+	assert.Equal(t, []int{3, 4, 5}, r.IDs(1, 7))
+	assert.Equal(t, []int{6}, r.IDs(1, 8))
+	assert.Empty(t, r.IDs(2, 7))
+}
@@ -0,0 +1,131 @@
+package tgbotapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"os"
+)
+
+// multipartBody is a multipart/form-data request body streaming its files,
+// with a length known in advance.
+type multipartBody struct {
+	contentType string
+	length      int64
+	readers     []io.Reader
+	closers     []io.Closer
+}
+
+// newMultipartBody prepares a body with the params as fields, followed by
+// the files. Files given as url.URL are sent as fields.
+func newMultipartBody(params map[string]string, files []RequestFile) (*multipartBody, error) {
+	body := &multipartBody{}
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	body.contentType = w.FormDataContentType()
+
+	fields := make(map[string]string, len(params))
+	for key, value := range params {
+		fields[key] = value
+	}
+	for _, file := range files {
+		if u, ok := file.File.(url.URL); ok {
+			fields[file.Name] = u.String()
+		}
+	}
+
+	for key, value := range fields {
+		if err := w.WriteField(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range files {
+		var (
+			name   string
+			size   int64
+			reader io.Reader
+		)
+
+		switch f := file.File.(type) {
+		case string:
+			fileHandle, err := os.Open(f)
+			if err != nil {
+				body.Close()
+				return nil, err
+			}
+			body.closers = append(body.closers, fileHandle)
+
+			fi, err := fileHandle.Stat()
+			if err != nil {
+				body.Close()
+				return nil, err
+			}
+
+			name, size, reader = fileHandle.Name(), fi.Size(), fileHandle
+		case FileBytes:
+			name, size, reader = f.Name, int64(len(f.Bytes)), bytes.NewReader(f.Bytes)
+		case FileReader:
+			if f.Size != -1 {
+				name, size, reader = f.Name, f.Size, f.Reader
+				break
+			}
+
+			data, err := ioutil.ReadAll(f.Reader)
+			if err != nil {
+				body.Close()
+				return nil, err
+			}
+
+			name, size, reader = f.Name, int64(len(data)), bytes.NewReader(data)
+		case url.URL:
+			continue
+		default:
+			body.Close()
+			return nil, errors.New(ErrBadFileType)
+		}
+
+		if _, err := w.CreateFormFile(file.Name, name); err != nil {
+			body.Close()
+			return nil, err
+		}
+		body.add(buf)
+		body.readers = append(body.readers, io.LimitReader(reader, size))
+		body.length += size
+	}
+
+	if err := w.Close(); err != nil {
+		body.Close()
+		return nil, err
+	}
+	body.add(buf)
+
+	return body, nil
+}
+
+// add appends the content written to buf so far, and resets it.
+func (body *multipartBody) add(buf *bytes.Buffer) {
+	data := append([]byte(nil), buf.Bytes()...)
+	buf.Reset()
+
+	body.readers = append(body.readers, bytes.NewReader(data))
+	body.length += int64(len(data))
+}
+
+// Reader returns the content of the body.
+func (body *multipartBody) Reader() io.Reader {
+	return io.MultiReader(body.readers...)
+}
+
+// Close closes the files opened for the body.
+func (body *multipartBody) Close() error {
+	for _, c := range body.closers {
+		c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package tgbotapi
+
+import (
+	"sync"
+
+	"github.com/byepp/util/fileutil"
+	"github.com/byepp/util/jsonutil"
+)
+
+// OffsetState is the stored progress of a Poller.
+//
+// Every update before Offset was handled. Processed lists the updates from
+// Offset on that were handled too, while an earlier one was still being
+// handled, so that they are not handled again after a restart.
+type OffsetState struct {
+	Offset    int   `json:"offset"`
+	Processed []int `json:"processed,omitempty"`
+}
+
+// OffsetStore persists the progress of a Poller.
+//
+// Implementations must be safe for concurrent use.
+type OffsetStore interface {
+	// Load returns the saved state, or the zero state if there is none.
+	Load() (OffsetState, error)
+	// Save stores the state.
+	Save(state OffsetState) error
+}
+
+// MemoryOffsetStore is an OffsetStore keeping the state in memory.
+type MemoryOffsetStore struct {
+	mu    sync.Mutex
+	state OffsetState
+}
+
+// NewMemoryOffsetStore creates an empty MemoryOffsetStore.
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{}
+}
+
+// Load returns the saved state.
+func (s *MemoryOffsetStore) Load() (OffsetState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state, nil
+}
+
+// Save stores the state.
+func (s *MemoryOffsetStore) Save(state OffsetState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	return nil
+}
+
+// FileOffsetStore is an OffsetStore saving the state to a JSON file, so
+// that polling resumes where it stopped after a restart.
+type FileOffsetStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileOffsetStore creates a FileOffsetStore saving to path.
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{path: path}
+}
+
+// Load reads the state from the file. A missing file is the zero state.
+func (s *FileOffsetStore) Load() (OffsetState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state OffsetState
+	if !fileutil.IsFileExist(s.path) {
+		return state, nil
+	}
+
+	err := jsonutil.LoadFile(s.path, &state)
+	return state, err
+}
+
+// Save writes the state to the file.
+func (s *FileOffsetStore) Save(state OffsetState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return jsonutil.SaveFile(s.path, state)
+}
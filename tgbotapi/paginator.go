@@ -0,0 +1,192 @@
+package tgbotapi
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// DefaultPageSize is the number of item buttons a Paginator shows per page.
+const DefaultPageSize = 5
+
+// Paginator renders a list of buttons into pages of an inline keyboard,
+// with a row of navigation buttons below them.
+//
+// Navigation buttons carry the target page encoded with the Paginator's
+// codec. Register the Paginator on a Router, or call HandleCallback, to
+// switch pages by editing the reply markup of the message.
+type Paginator struct {
+	PageSize int
+	// Columns is the number of item buttons per row.
+	Columns  int
+	PrevText string
+	NextText string
+
+	codec *CallbackCodec
+	items func() []InlineKeyboardButton
+}
+
+// NewPaginator creates a Paginator over the buttons returned by items,
+// which is called every time a page is rendered. codec must not be shared
+// with other callbacks.
+func NewPaginator(codec *CallbackCodec, items func() []InlineKeyboardButton) *Paginator {
+	return &Paginator{
+		PageSize: DefaultPageSize,
+		Columns:  1,
+		PrevText: "«",
+		NextText: "»",
+		codec:    codec,
+		items:    items,
+	}
+}
+
+// Pages returns the number of pages, at least one.
+func (p *Paginator) Pages() int {
+	return p.pages(len(p.items()))
+}
+
+// Markup returns the keyboard of page, counting from zero. Pages out of
+// range are clamped.
+func (p *Paginator) Markup(page int) (InlineKeyboardMarkup, error) {
+	items := p.items()
+	pages := p.pages(len(items))
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * p.pageSize()
+	end := start + p.pageSize()
+	if end > len(items) {
+		end = len(items)
+	}
+
+	var rows [][]InlineKeyboardButton
+	columns := p.Columns
+	if columns < 1 {
+		columns = 1
+	}
+	for i := start; i < end; i += columns {
+		j := i + columns
+		if j > end {
+			j = end
+		}
+		rows = append(rows, NewInlineKeyboardRow(items[i:j]...))
+	}
+
+	if pages > 1 {
+		var nav []InlineKeyboardButton
+		if page > 0 {
+			button, err := p.codec.Button(p.PrevText, page-1)
+			if err != nil {
+				return InlineKeyboardMarkup{}, err
+			}
+			nav = append(nav, button)
+		}
+
+		button, err := p.codec.Button(strconv.Itoa(page+1)+"/"+strconv.Itoa(pages), page)
+		if err != nil {
+			return InlineKeyboardMarkup{}, err
+		}
+		nav = append(nav, button)
+
+		if page < pages-1 {
+			button, err := p.codec.Button(p.NextText, page+1)
+			if err != nil {
+				return InlineKeyboardMarkup{}, err
+			}
+			nav = append(nav, button)
+		}
+		rows = append(rows, nav)
+	}
+
+	return NewInlineKeyboardMarkup(rows...), nil
+}
+
+// EditConfig returns the config switching the message of a navigation
+// callback query to the requested page.
+func (p *Paginator) EditConfig(query *CallbackQuery) (EditMessageReplyMarkupConfig, error) {
+	data, err := p.codec.Decode(query.Data)
+	if err != nil {
+		return EditMessageReplyMarkupConfig{}, err
+	}
+
+	page, err := data.Int(0)
+	if err != nil {
+		return EditMessageReplyMarkupConfig{}, err
+	}
+
+	markup, err := p.Markup(page)
+	if err != nil {
+		return EditMessageReplyMarkupConfig{}, err
+	}
+
+	config := EditMessageReplyMarkupConfig{
+		BaseEdit: BaseEdit{
+			InlineMessageID: query.InlineMessageID,
+			ReplyMarkup:     &markup,
+		},
+	}
+	if query.Message != nil {
+		config.ChatID = query.Message.Chat.ID
+		config.MessageID = query.Message.MessageID
+	}
+
+	return config, nil
+}
+
+// HandleCallback switches pages for a navigation callback query and
+// answers it.
+func (p *Paginator) HandleCallback(bot *BotAPI, update Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	if err := p.edit(bot, query); err != nil && !IsMessageNotModified(err) {
+		log.Error("paginate", zap.String("data", query.Data), zap.Error(err))
+	}
+
+	if _, err := bot.AnswerCallbackQuery(NewCallback(query.ID, "")); err != nil {
+		log.Error("answer callback query", zap.String("id", query.ID), zap.Error(err))
+	}
+}
+
+// Register adds a route handling the Paginator's navigation callbacks.
+func (p *Paginator) Register(r *Router) {
+	r.Handle(p.codec.Matcher(), p.HandleCallback)
+}
+
+// edit switches the message of query to the requested page.
+func (p *Paginator) edit(bot *BotAPI, query *CallbackQuery) error {
+	config, err := p.EditConfig(query)
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.request(context.Background(), config, false)
+	return err
+}
+
+// pageSize returns PageSize, or DefaultPageSize if it is not positive.
+func (p *Paginator) pageSize() int {
+	if p.PageSize < 1 {
+		return DefaultPageSize
+	}
+
+	return p.PageSize
+}
+
+// pages returns the number of pages for n items.
+func (p *Paginator) pages(n int) int {
+	size := p.pageSize()
+	pages := (n + size - 1) / size
+	if pages < 1 {
+		pages = 1
+	}
+
+	return pages
+}
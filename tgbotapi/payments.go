@@ -0,0 +1,58 @@
+package tgbotapi
+
+import (
+	"strings"
+
+	"github.com/byepp/util/decimalutil"
+	"github.com/shopspring/decimal"
+)
+
+// defaultCurrencyExponent is the number of decimal places of currencies
+// missing from CurrencyExponents.
+const defaultCurrencyExponent = 2
+
+// CurrencyExponents is the number of decimal places of the minor unit of
+// currencies that don't have two, such as cents for USD. Amounts in
+// LabeledPrice and payments are integers in the minor unit.
+var CurrencyExponents = map[string]int32{
+	CurrencyStars: 0,
+	"CLP":         0,
+	"ISK":         0,
+	"JPY":         0,
+	"KRW":         0,
+	"PYG":         0,
+	"UGX":         0,
+	"VND":         0,
+	"BHD":         3,
+	"JOD":         3,
+	"KWD":         3,
+	"OMR":         3,
+	"TND":         3,
+}
+
+// CurrencyExponent returns the number of decimal places of the minor unit
+// of currency.
+func CurrencyExponent(currency string) int32 {
+	if exp, ok := CurrencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+
+	return defaultCurrencyExponent
+}
+
+// AmountToDecimal converts an amount in the minor unit of currency to a
+// decimal amount, such as 1234 USD to 12.34.
+func AmountToDecimal(currency string, amount int) decimal.Decimal {
+	return decimalutil.FromMinorUnits(int64(amount), CurrencyExponent(currency))
+}
+
+// DecimalToAmount converts a decimal amount to the minor unit of currency,
+// such as 12.34 USD to 1234. Extra decimal places are rounded.
+func DecimalToAmount(currency string, amount decimal.Decimal) int {
+	return int(decimalutil.ToMinorUnits(amount, CurrencyExponent(currency)))
+}
+
+// NewLabeledPrice creates a LabeledPrice of a decimal amount of currency.
+func NewLabeledPrice(label, currency string, amount decimal.Decimal) LabeledPrice {
+	return LabeledPrice{Label: label, Amount: DecimalToAmount(currency, amount)}
+}
@@ -0,0 +1,42 @@
+package tgbotapi
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrencyAmounts(t *testing.T) {
+	assert.Equal(t, "12.34", AmountToDecimal("USD", 1234).String())
+	assert.Equal(t, "1234", AmountToDecimal("JPY", 1234).String())
+	assert.Equal(t, "1.234", AmountToDecimal("KWD", 1234).String())
+	assert.Equal(t, "50", AmountToDecimal(CurrencyStars, 50).String())
+
+	assert.Equal(t, 1235, DecimalToAmount("eur", decimal.RequireFromString("12.345")))
+	assert.Equal(t, 500, DecimalToAmount("JPY", decimal.NewFromInt(500)))
+	assert.Equal(t, LabeledPrice{Label: "Total", Amount: 999}, NewLabeledPrice("Total", "USD", decimal.RequireFromString("9.99")))
+}
+
+func TestInvoiceValues(t *testing.T) {
+	invoice := NewInvoice(1, "Title", "Description", "payload", "token", "", "USD", &[]LabeledPrice{{Label: "Item", Amount: 100}})
+	invoice.MaxTipAmount = 500
+	invoice.SuggestedTipAmounts = []int{100, 200}
+	invoice.ProviderData = `{"a":1}`
+	invoice.SendEmailToProvider = true
+
+	v, err := invoice.values()
+	assert.Nil(t, err)
+	assert.Equal(t, "token", v.Get("provider_token"))
+	assert.Equal(t, "500", v.Get("max_tip_amount"))
+	assert.Equal(t, "[100,200]", v.Get("suggested_tip_amounts"))
+	assert.Equal(t, `{"a":1}`, v.Get("provider_data"))
+	assert.Equal(t, "true", v.Get("send_email_to_provider"))
+	assert.False(t, v.Has("start_parameter"))
+
+	v, err = NewStarsInvoiceLink("Title", "Description", "payload", 50).values()
+	assert.Nil(t, err)
+	assert.Equal(t, CurrencyStars, v.Get("currency"))
+	assert.False(t, v.Has("provider_token"))
+	assert.Equal(t, `[{"label":"Title","amount":50}]`, v.Get("prices"))
+}
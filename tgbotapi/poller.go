@@ -0,0 +1,322 @@
+package tgbotapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ackPollInterval is how long a Poller started with StartPollingWithStore
+// waits for an acknowledgement before polling for new updates again, when
+// it only received updates still being handled.
+const ackPollInterval = time.Second
+
+// Poller receives updates with long polling and delivers them on a channel.
+type Poller struct {
+	bot    *BotAPI
+	config UpdateConfig
+	ch     chan Update
+	done   chan struct{}
+
+	// onError is called when getting updates failed.
+	onError func(err error)
+
+	mu     sync.Mutex
+	offset int
+
+	// The fields below are only used by a Poller started with
+	// StartPollingWithStore.
+	store     OffsetStore
+	acks      chan struct{}
+	next      int
+	delivered []int
+	acked     map[int]bool
+}
+
+// StartPolling starts receiving updates in the background until ctx is
+// cancelled. Once polling stops the updates channel is closed.
+//
+// Offset in config is the first update to receive, pass the value of
+// Offset from a previous Poller to resume where it stopped.
+func (bot *BotAPI) StartPolling(ctx context.Context, config UpdateConfig) *Poller {
+	p := newPoller(bot, config)
+
+	go p.run(ctx)
+
+	return p
+}
+
+// newPoller creates a Poller that is not started.
+func newPoller(bot *BotAPI, config UpdateConfig) *Poller {
+	return &Poller{
+		bot:    bot,
+		config: config,
+		ch:     make(chan Update, bot.Buffer),
+		done:   make(chan struct{}),
+		offset: config.Offset,
+	}
+}
+
+// StartPollingWithStore starts receiving updates like StartPolling, but
+// only advances the offset once updates are acknowledged with Ack, and
+// saves it to store.
+//
+// Polling resumes from the offset saved in store, or from Offset in config
+// if it is later. Updates delivered but not acknowledged before a restart
+// are delivered again, while updates acknowledged out of order are not.
+// Telegram only forgets updates before the acknowledged offset, so that
+// none are lost if the process stops.
+func (bot *BotAPI) StartPollingWithStore(ctx context.Context, config UpdateConfig, store OffsetStore) (*Poller, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Offset > config.Offset {
+		config.Offset = state.Offset
+	}
+
+	p := newPoller(bot, config)
+	p.store = store
+	p.acks = make(chan struct{}, 1)
+	p.next = config.Offset
+	p.acked = make(map[int]bool)
+
+	for _, id := range state.Processed {
+		if id >= config.Offset {
+			p.acked[id] = true
+		}
+	}
+
+	go p.runWithAck(ctx)
+
+	return p, nil
+}
+
+// Updates returns the channel updates are delivered on.
+func (p *Poller) Updates() UpdatesChannel {
+	return p.ch
+}
+
+// Done returns a channel that is closed once polling has stopped.
+func (p *Poller) Done() <-chan struct{} {
+	return p.done
+}
+
+// Offset returns the offset following the last update delivered on the
+// updates channel. Updates fetched but not yet delivered when polling
+// stopped are not included, so they are received again on restart.
+//
+// For a Poller started with StartPollingWithStore, it is the offset
+// following the last update acknowledged along with all before it.
+func (p *Poller) Offset() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.offset
+}
+
+// Ack records that an update was handled and saves the progress. It does
+// nothing for a Poller started with StartPolling.
+func (p *Poller) Ack(updateID int) error {
+	if p.store == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if updateID < p.offset || updateID >= p.next || p.acked[updateID] {
+		return nil
+	}
+
+	p.acked[updateID] = true
+	p.advance()
+
+	select {
+	case p.acks <- struct{}{}:
+	default:
+	}
+
+	return p.store.Save(p.state())
+}
+
+// Middleware returns a Middleware acknowledging every update once its
+// handler returns. Updates whose handler panics are acknowledged as well,
+// so that they don't hold back the offset forever.
+func (p *Poller) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			defer func() {
+				if err := p.Ack(update.UpdateID); err != nil {
+					log.Error("save update offset", zap.Int("update_id", update.UpdateID), zap.Error(err))
+				}
+			}()
+
+			next(bot, update)
+		}
+	}
+}
+
+// advance moves the offset past the acknowledged updates at the start of
+// the delivered ones. The caller must hold mu.
+func (p *Poller) advance() {
+	for len(p.delivered) > 0 && p.acked[p.delivered[0]] {
+		p.offset = p.delivered[0] + 1
+		delete(p.acked, p.delivered[0])
+		p.delivered = p.delivered[1:]
+	}
+}
+
+// state returns the progress to save. The caller must hold mu.
+func (p *Poller) state() OffsetState {
+	state := OffsetState{Offset: p.offset}
+	for _, id := range p.delivered {
+		if p.acked[id] {
+			state.Processed = append(state.Processed, id)
+		}
+	}
+
+	return state
+}
+
+// commit records that every update before offset was delivered.
+func (p *Poller) commit(offset int) {
+	p.mu.Lock()
+	p.offset = offset
+	p.mu.Unlock()
+}
+
+// retry reports a failure to get updates and waits before trying again.
+// It returns false if ctx was cancelled.
+func (p *Poller) retry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	log.Error("bot.GetUpdates failed", zap.Error(err))
+	log.Info("Failed to get updates, retrying in 3 seconds...")
+	if p.onError != nil {
+		p.onError(err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Second * 3):
+		return true
+	}
+}
+
+// run polls for updates until ctx is cancelled.
+func (p *Poller) run(ctx context.Context) {
+	defer func() {
+		close(p.ch)
+		close(p.done)
+	}()
+
+	config := p.config
+
+	for ctx.Err() == nil {
+		updates, err := p.bot.GetUpdatesWithContext(ctx, config)
+		if err != nil {
+			if !p.retry(ctx, err) {
+				return
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			if update.UpdateID < config.Offset {
+				continue
+			}
+
+			select {
+			case p.ch <- update:
+				config.Offset = update.UpdateID + 1
+				p.commit(config.Offset)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// runWithAck polls for updates until ctx is cancelled, from the offset of
+// the acknowledged updates.
+func (p *Poller) runWithAck(ctx context.Context) {
+	defer func() {
+		close(p.ch)
+		close(p.done)
+	}()
+
+	config := p.config
+
+	for ctx.Err() == nil {
+		config.Offset = p.Offset()
+
+		updates, err := p.bot.GetUpdatesWithContext(ctx, config)
+		if err != nil {
+			if !p.retry(ctx, err) {
+				return
+			}
+			continue
+		}
+
+		fresh := false
+		for _, update := range updates {
+			deliver, ok := p.receive(update.UpdateID)
+			if !ok {
+				continue
+			}
+			fresh = true
+			if !deliver {
+				continue
+			}
+
+			select {
+			case p.ch <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Only updates still being handled were received, wait for one
+		// of them to be acknowledged rather than fetching them again. The
+		// wait is bounded, as an update may never be acknowledged and
+		// would otherwise hold back the updates after it.
+		if !fresh && len(updates) > 0 {
+			timer := time.NewTimer(ackPollInterval)
+			select {
+			case <-p.acks:
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			timer.Stop()
+		}
+	}
+}
+
+// receive records a fetched update. ok is false if it was received
+// before, deliver is false if it was handled before a restart.
+func (p *Poller) receive(updateID int) (deliver, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if updateID < p.next {
+		return false, false
+	}
+
+	p.next = updateID + 1
+	p.delivered = append(p.delivered, updateID)
+	if p.acked[updateID] {
+		p.advance()
+		return false, true
+	}
+
+	return true, true
+}
@@ -0,0 +1,108 @@
+package tgbotapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default intervals between messages, following the limits documented at
+// https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+const (
+	// DefaultGlobalInterval allows about 30 messages per second overall.
+	DefaultGlobalInterval = time.Second / 30
+	// DefaultPrivateChatInterval allows one message per second to a chat.
+	DefaultPrivateChatInterval = time.Second
+	// DefaultGroupChatInterval allows 20 messages per minute to a group.
+	DefaultGroupChatInterval = time.Minute / 20
+)
+
+// maxTrackedChats is the number of chats tracked before chats that may
+// send again are forgotten.
+const maxTrackedChats = 1024
+
+// RateLimiter spaces outgoing messages so they stay within the global and
+// per-chat limits of Telegram. It is safe for concurrent use.
+type RateLimiter struct {
+	GlobalInterval      time.Duration
+	PrivateChatInterval time.Duration
+	GroupChatInterval   time.Duration
+
+	mu     sync.Mutex
+	global time.Time
+	chats  map[string]time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with the default intervals.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		GlobalInterval:      DefaultGlobalInterval,
+		PrivateChatInterval: DefaultPrivateChatInterval,
+		GroupChatInterval:   DefaultGroupChatInterval,
+		chats:               make(map[string]time.Time),
+	}
+}
+
+// Wait blocks until a message may be sent to the chat, or ctx is done.
+//
+// chatID is the chat_id parameter of the request, either a numeric ID or
+// a channel username. Negative IDs and usernames use the group interval.
+func (l *RateLimiter) Wait(ctx context.Context, chatID string) error {
+	for {
+		delay := l.reserve(chatID, time.Now())
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve claims a slot for the chat at now, or returns how long to wait
+// before trying again.
+func (l *RateLimiter) reserve(chatID string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.chats == nil {
+		l.chats = make(map[string]time.Time)
+	}
+
+	if next, ok := l.chats[chatID]; ok && next.After(now) {
+		return next.Sub(now)
+	}
+	if l.global.After(now) {
+		return l.global.Sub(now)
+	}
+
+	if len(l.chats) >= maxTrackedChats {
+		for id, next := range l.chats {
+			if !next.After(now) {
+				delete(l.chats, id)
+			}
+		}
+	}
+
+	l.global = now.Add(l.GlobalInterval)
+	if chatID != "" {
+		l.chats[chatID] = now.Add(l.chatInterval(chatID))
+	}
+
+	return 0
+}
+
+// chatInterval returns the interval between messages to the chat.
+func (l *RateLimiter) chatInterval(chatID string) time.Duration {
+	if strings.HasPrefix(chatID, "-") || strings.HasPrefix(chatID, "@") {
+		return l.GroupChatInterval
+	}
+
+	return l.PrivateChatInterval
+}
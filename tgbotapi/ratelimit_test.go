@@ -0,0 +1,51 @@
+package tgbotapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterGlobalInterval(t *testing.T) {
+	l := NewRateLimiter()
+	now := time.Now()
+
+	assert.Zero(t, l.reserve("1", now))
+	assert.Equal(t, DefaultGlobalInterval, l.reserve("2", now))
+	assert.Zero(t, l.reserve("2", now.Add(DefaultGlobalInterval)))
+}
+
+func TestRateLimiterChatInterval(t *testing.T) {
+	l := NewRateLimiter()
+	l.GlobalInterval = 0
+	now := time.Now()
+
+	assert.Zero(t, l.reserve("1", now))
+	assert.Equal(t, DefaultPrivateChatInterval, l.reserve("1", now))
+	assert.Zero(t, l.reserve("1", now.Add(DefaultPrivateChatInterval)))
+
+	now = now.Add(DefaultPrivateChatInterval)
+	assert.Zero(t, l.reserve("-100", now))
+	assert.Equal(t, DefaultGroupChatInterval, l.reserve("-100", now))
+	assert.Zero(t, l.reserve("@channel", now))
+	assert.Equal(t, DefaultGroupChatInterval, l.reserve("@channel", now))
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter()
+	l.PrivateChatInterval = 20 * time.Millisecond
+
+	start := time.Now()
+	assert.Nil(t, l.Wait(context.Background(), "1"))
+	assert.Nil(t, l.Wait(context.Background(), "1"))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	l.PrivateChatInterval = time.Hour
+	assert.Nil(t, l.Wait(context.Background(), "2"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx, "2"))
+}
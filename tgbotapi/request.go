@@ -0,0 +1,190 @@
+package tgbotapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Request is a config of an API method with a result of type T.
+//
+// Configs name their method with method() and declare their result with
+// newResult(), so that Do can send them without a BotAPI method written
+// for each of them.
+type Request[T any] interface {
+	Chattable
+	newResult() *T
+}
+
+// jsonRequest is a config sent as a JSON body instead of a form, for
+// methods taking nested objects.
+type jsonRequest interface {
+	jsonBody() (interface{}, error)
+}
+
+// multiFileable is a config uploading several files in one request.
+type multiFileable interface {
+	params() (map[string]string, []RequestFile, error)
+}
+
+// Do sends a request and returns its decoded result.
+//
+// Requests returning messages wait for the Limiter of their chat. Edits of
+// inline messages, for which Telegram returns true, result in an empty
+// Message.
+func Do[T any](bot *BotAPI, config Request[T]) (T, error) {
+	return DoWithContext(context.Background(), bot, config)
+}
+
+// DoWithContext sends a request like Do, aborting it when ctx is cancelled.
+func DoWithContext[T any](ctx context.Context, bot *BotAPI, config Request[T]) (T, error) {
+	var zero T
+
+	result := config.newResult()
+
+	var limit bool
+	switch interface{}(result).(type) {
+	case *Message, *[]Message:
+		limit = true
+	}
+
+	resp, err := bot.request(ctx, config, limit)
+	if err != nil {
+		return zero, err
+	}
+
+	if err := decodeResult(resp.Result, result); err != nil {
+		return zero, err
+	}
+
+	bot.debugLog(config.method(), nil, *result)
+
+	return *result, nil
+}
+
+// request sends a config as a JSON body, a multipart upload or a form,
+// whichever it needs. If limit is true, it waits for the Limiter of the
+// chat first.
+func (bot *BotAPI) request(ctx context.Context, config Chattable, limit bool) (APIResponse, error) {
+	v, err := config.values()
+	if err != nil {
+		return APIResponse{}, err
+	}
+
+	if limit {
+		if err := bot.waitLimiter(ctx, v.Get("chat_id")); err != nil {
+			return APIResponse{}, err
+		}
+	}
+
+	switch c := config.(type) {
+	case jsonRequest:
+		body, err := c.jsonBody()
+		if err != nil {
+			return APIResponse{}, err
+		}
+
+		return bot.MakeJSONRequestWithContext(ctx, config.method(), body)
+	case multiFileable:
+		params, files, err := c.params()
+		if err != nil {
+			return APIResponse{}, err
+		}
+		if len(files) != 0 {
+			return bot.UploadFilesWithContext(ctx, config.method(), params, files)
+		}
+	case Fileable:
+		if !c.useExistingFile() {
+			params, err := c.params()
+			if err != nil {
+				return APIResponse{}, err
+			}
+
+			return bot.UploadFileWithContext(ctx, config.method(), params, c.name(), c.getFile())
+		}
+	}
+
+	return bot.MakeRequestWithContext(ctx, config.method(), v)
+}
+
+// MakeJSONRequest makes a request to a specific endpoint with the body
+// encoded as JSON.
+func (bot *BotAPI) MakeJSONRequest(endpoint string, body interface{}) (APIResponse, error) {
+	return bot.MakeJSONRequestWithContext(context.Background(), endpoint, body)
+}
+
+// MakeJSONRequestWithContext makes a request like MakeJSONRequest, aborting
+// it when ctx is cancelled.
+//
+// Requests are repeated like in MakeRequestWithContext. Requests to a
+// group that was upgraded are repeated with the new chat_id if body is
+// encoded as a JSON object.
+func (bot *BotAPI) MakeJSONRequestWithContext(ctx context.Context, endpoint string, body interface{}) (APIResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return APIResponse{}, err
+	}
+
+	chatID := jsonChatID(data)
+	for attempt := 0; ; attempt++ {
+		resp, err := bot.post(ctx, endpoint, "application/json", bytes.NewReader(data))
+		if err == nil || attempt >= bot.MaxRetries {
+			return resp, err
+		}
+
+		newChatID, retry := bot.prepareRetry(ctx, endpoint, err, chatID)
+		if !retry {
+			return resp, err
+		}
+
+		if newChatID != chatID {
+			if data, err = setJSONChatID(data, newChatID); err != nil {
+				return resp, err
+			}
+			chatID = newChatID
+		}
+	}
+}
+
+// jsonChatID returns the chat_id of a JSON object, or "" if it has none.
+func jsonChatID(data []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+
+	var username string
+	if err := json.Unmarshal(fields["chat_id"], &username); err == nil {
+		return username
+	}
+
+	var chatID json.Number
+	if err := json.Unmarshal(fields["chat_id"], &chatID); err == nil {
+		return chatID.String()
+	}
+
+	return ""
+}
+
+// setJSONChatID returns a JSON object with its chat_id replaced.
+func setJSONChatID(data []byte, chatID string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	fields["chat_id"] = json.RawMessage(chatID)
+
+	return json.Marshal(fields)
+}
+
+// decodeResult decodes the result of a response into out. A true result
+// into a Message is left empty, as Telegram returns it for edits of inline
+// messages.
+func decodeResult(result json.RawMessage, out interface{}) error {
+	if _, ok := out.(*Message); ok && string(result) == "true" {
+		return nil
+	}
+
+	return json.Unmarshal(result, out)
+}
@@ -0,0 +1,339 @@
+package tgbotapi
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HandlerFunc handles a single Update received by a Router.
+type HandlerFunc func(bot *BotAPI, update Update)
+
+// Middleware wraps a HandlerFunc with additional behavior, such as logging
+// or authorization. A Middleware may decide not to call next at all.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Matcher reports if a route is responsible for an Update.
+type Matcher func(update Update) bool
+
+// route is a Matcher bound to the handler it selects.
+type route struct {
+	match   Matcher
+	handler HandlerFunc
+}
+
+// Router dispatches updates to registered handlers.
+//
+// Routes are checked in the order they were registered and the first
+// matching route wins. Updates no route matches are passed to the handler
+// set with NotFound, if any. Routes and middlewares should be registered
+// before the Router starts receiving updates.
+type Router struct {
+	bot         *BotAPI
+	routes      []route
+	middlewares []Middleware
+	notFound    HandlerFunc
+	commands    []BotCommand
+	publish     []SetMyCommandsConfig
+}
+
+// NewRouter creates a new Router for the bot.
+func NewRouter(bot *BotAPI) *Router {
+	return &Router{
+		bot: bot,
+	}
+}
+
+// Use appends middlewares to the chain every update passes through.
+//
+// Middlewares run in the order they were added, before any route is
+// selected.
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Handle registers a handler for updates accepted by match.
+func (r *Router) Handle(match Matcher, handler HandlerFunc) {
+	r.routes = append(r.routes, route{match: match, handler: handler})
+}
+
+// Command registers a handler for a bot command, without the leading slash.
+//
+// Commands addressed to another bot with the "/command@bot" syntax are
+// ignored.
+func (r *Router) Command(command string, handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return r.isCommand(update.Message, command)
+	}, handler)
+}
+
+// CommandWithDescription registers a handler for a bot command like
+// Command, and lists it with description in the command menu published by
+// PublishCommands.
+func (r *Router) CommandWithDescription(command, description string, handler HandlerFunc) {
+	r.Command(command, handler)
+	r.commands = append(r.commands, NewBotCommand(command, description))
+}
+
+// Commands returns the commands registered with a description, in the
+// order they were registered.
+func (r *Router) Commands() []BotCommand {
+	return r.commands
+}
+
+// PublishCommands sets the registered commands as the command list of
+// config's scope and language.
+func (r *Router) PublishCommands(config SetMyCommandsConfig) error {
+	config.Commands = r.Commands()
+	_, err := r.bot.SetMyCommands(config)
+
+	return err
+}
+
+// AutoPublishCommands makes Run publish the registered commands with each
+// config before handling updates. Without configs, they are published to
+// the default scope.
+func (r *Router) AutoPublishCommands(configs ...SetMyCommandsConfig) {
+	if len(configs) == 0 {
+		configs = []SetMyCommandsConfig{NewSetMyCommands()}
+	}
+	r.publish = append(r.publish, configs...)
+}
+
+// CallbackQuery registers a handler for callback queries with data
+// starting with prefix. An empty prefix matches every callback query.
+func (r *Router) CallbackQuery(prefix string, handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, prefix)
+	}, handler)
+}
+
+// InlineQuery registers a handler for inline queries.
+func (r *Router) InlineQuery(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.InlineQuery != nil
+	}, handler)
+}
+
+// ChosenInlineResult registers a handler for chosen inline results.
+func (r *Router) ChosenInlineResult(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.ChosenInlineResult != nil
+	}, handler)
+}
+
+// Poll registers a handler for updates about the state of polls sent by
+// the bot, such as new votes in anonymous polls.
+func (r *Router) Poll(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.Poll != nil
+	}, handler)
+}
+
+// PollAnswer registers a handler for votes in non-anonymous polls sent by
+// the bot.
+func (r *Router) PollAnswer(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.PollAnswer != nil
+	}, handler)
+}
+
+// MyChatMember registers a handler for changes of the bot's own status
+// in chats, such as being added to a group or blocked by a user.
+func (r *Router) MyChatMember(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.MyChatMember != nil
+	}, handler)
+}
+
+// ChatMember registers a handler for changes of the status of chat
+// members. The bot must be an administrator and request
+// UpdateTypeChatMember in AllowedUpdates to receive them.
+func (r *Router) ChatMember(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.ChatMember != nil
+	}, handler)
+}
+
+// ChatJoinRequest registers a handler for requests to join chats the bot
+// administers.
+func (r *Router) ChatJoinRequest(handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.ChatJoinRequest != nil
+	}, handler)
+}
+
+// Topic registers a handler for messages, and callback queries on
+// messages, sent in a topic of a forum supergroup.
+//
+// Messages in the General topic have no thread ID, so use threadID 0 for
+// it. Register routes matching specific topics before the others.
+func (r *Router) Topic(threadID int, handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		message := update.Message
+		if message == nil && update.CallbackQuery != nil {
+			message = update.CallbackQuery.Message
+		}
+		if message == nil || message.Chat == nil || !message.Chat.IsForum {
+			return false
+		}
+
+		if threadID == 0 {
+			return !message.IsTopicMessage
+		}
+
+		return message.IsTopicMessage && message.MessageThreadID == threadID
+	}, handler)
+}
+
+// ContentType registers a handler for new messages carrying the given
+// kind of content, such as ContentTypePhoto or ContentTypeLocation.
+func (r *Router) ContentType(contentType string, handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.Message != nil && update.Message.ContentType() == contentType
+	}, handler)
+}
+
+// ChatType registers a handler for new messages sent in chats of the given
+// type, such as "private", "group", "supergroup" or "channel".
+func (r *Router) ChatType(chatType string, handler HandlerFunc) {
+	r.Handle(func(update Update) bool {
+		return update.Message != nil && update.Message.Chat != nil && update.Message.Chat.Type == chatType
+	}, handler)
+}
+
+// NotFound sets the handler for updates no route matches.
+func (r *Router) NotFound(handler HandlerFunc) {
+	r.notFound = handler
+}
+
+// HandleUpdate passes an update through the middlewares to its handler.
+func (r *Router) HandleUpdate(update Update) {
+	Chain(r.dispatch, r.middlewares...)(r.bot, update)
+}
+
+// Run handles updates from the channel until it is closed.
+//
+// If AutoPublishCommands was called, the commands are published first.
+// Failures to publish are logged and don't stop the Router.
+func (r *Router) Run(updates UpdatesChannel) {
+	for _, config := range r.publish {
+		if err := r.PublishCommands(config); err != nil {
+			log.Error("publish commands", zap.Error(err))
+		}
+	}
+
+	for update := range updates {
+		r.HandleUpdate(update)
+	}
+}
+
+// dispatch calls the handler of the first matching route.
+func (r *Router) dispatch(bot *BotAPI, update Update) {
+	for _, rt := range r.routes {
+		if rt.match(update) {
+			rt.handler(bot, update)
+			return
+		}
+	}
+
+	if r.notFound != nil {
+		r.notFound(bot, update)
+	}
+}
+
+// isCommand returns if the message is the command and is addressed
+// to this bot.
+func (r *Router) isCommand(message *Message, command string) bool {
+	if message == nil || !message.IsCommand() {
+		return false
+	}
+
+	if !strings.EqualFold(message.Command(), command) {
+		return false
+	}
+
+	withAt := message.CommandWithAt()
+	if i := strings.Index(withAt, "@"); i != -1 && r.bot != nil {
+		return strings.EqualFold(withAt[i+1:], r.bot.Self.UserName)
+	}
+
+	return true
+}
+
+// Chain wraps handler with middlewares, so that the first middleware is
+// the outermost one.
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// LoggingMiddleware logs every update along with the time spent handling it.
+func LoggingMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			start := time.Now()
+			next(bot, update)
+
+			fields := []zap.Field{zap.Int("update_id", update.UpdateID), zap.Duration("elapsed", time.Since(start))}
+			if user := update.SentFrom(); user != nil {
+				fields = append(fields, zap.Int("user_id", user.ID))
+			}
+			if chat := update.FromChat(); chat != nil {
+				fields = append(fields, zap.Int64("chat_id", chat.ID))
+			}
+			log.Info("update handled", fields...)
+		}
+	}
+}
+
+// RecoverMiddleware recovers from panics raised by handlers, so that one
+// bad update does not stop the bot. The panic is logged with a stack trace.
+func RecoverMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Error("handler panicked",
+						zap.Int("update_id", update.UpdateID),
+						zap.String("panic", fmt.Sprint(err)),
+						zap.ByteString("stack", debug.Stack()))
+				}
+			}()
+
+			next(bot, update)
+		}
+	}
+}
+
+// AuthMiddleware only lets through updates accepted by allow. Rejected
+// updates are dropped silently.
+func AuthMiddleware(allow func(update Update) bool) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(bot *BotAPI, update Update) {
+			if allow(update) {
+				next(bot, update)
+			}
+		}
+	}
+}
+
+// AllowUsers returns an AuthMiddleware accepting only updates sent by the
+// given user IDs.
+func AllowUsers(userIDs ...int) Middleware {
+	allowed := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		allowed[id] = true
+	}
+
+	return AuthMiddleware(func(update Update) bool {
+		user := update.SentFrom()
+		return user != nil && allowed[user.ID]
+	})
+}
@@ -0,0 +1,73 @@
+package tgbotapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func commandUpdate(text string, length int) Update {
+	return Update{
+		Message: &Message{
+			Text:     text,
+			Chat:     &Chat{ID: 1, Type: "private"},
+			From:     &User{ID: 7},
+			Entities: &[]MessageEntity{{Type: "bot_command", Offset: 0, Length: length}},
+		},
+	}
+}
+
+func TestRouterCommand(t *testing.T) {
+	bot := &BotAPI{Self: User{UserName: "my_bot"}}
+	r := NewRouter(bot)
+
+	var got []string
+	r.Command("start", func(bot *BotAPI, update Update) {
+		got = append(got, "start:"+update.Message.CommandArguments())
+	})
+	r.NotFound(func(bot *BotAPI, update Update) {
+		got = append(got, "notfound")
+	})
+
+	r.HandleUpdate(commandUpdate("/start abc", 6))
+	r.HandleUpdate(commandUpdate("/start@my_bot", 13))
+	r.HandleUpdate(commandUpdate("/start@other_bot", 16))
+	r.HandleUpdate(commandUpdate("/help", 5))
+
+	assert.Equal(t, []string{"start:abc", "start:", "notfound", "notfound"}, got)
+}
+
+func TestRouterMiddlewareOrder(t *testing.T) {
+	r := NewRouter(&BotAPI{})
+
+	var got []string
+	mark := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(bot *BotAPI, update Update) {
+				got = append(got, name)
+				next(bot, update)
+			}
+		}
+	}
+	r.Use(mark("first"), mark("second"), AllowUsers(7))
+	r.CallbackQuery("page:", func(bot *BotAPI, update Update) {
+		got = append(got, "page")
+	})
+
+	r.HandleUpdate(Update{CallbackQuery: &CallbackQuery{From: &User{ID: 7}, Data: "page:2"}})
+	r.HandleUpdate(Update{CallbackQuery: &CallbackQuery{From: &User{ID: 8}, Data: "page:3"}})
+
+	assert.Equal(t, []string{"first", "second", "page", "first", "second"}, got)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	r := NewRouter(&BotAPI{})
+	r.Use(RecoverMiddleware())
+	r.ContentType(ContentTypeLocation, func(bot *BotAPI, update Update) {
+		panic("boom")
+	})
+
+	assert.NotPanics(t, func() {
+		r.HandleUpdate(Update{Message: &Message{Location: &Location{}}})
+	})
+}
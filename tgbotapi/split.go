@@ -0,0 +1,599 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of text lengths in UTF-16 code units, as measured after entities
+// are parsed.
+const (
+	MaxMessageTextLength = 4096
+	MaxCaptionLength     = 1024
+)
+
+// TextPart is a part of a split text along with its entities.
+type TextPart struct {
+	Text     string
+	Entities []MessageEntity
+}
+
+// textUnit is the smallest piece of text a split never breaks up.
+type textUnit struct {
+	raw   string
+	char  rune // zero for HTML tags and Markdown markers
+	width int  // visible length in UTF-16 code units
+}
+
+// markup is formatting open around a split, written as open at the start
+// of the next part and as close at the end of the previous one.
+type markup struct {
+	open  string
+	close string
+}
+
+// splitSeparators are the boundaries a split prefers, best first.
+var splitSeparators = []string{"\n\n", "\n", " "}
+
+// SplitText splits plain text into parts of at most limit UTF-16 code
+// units. Parts end on paragraph, line or word boundaries where possible.
+func SplitText(text string, limit int) []string {
+	return splitPlain(text, limit, limit)
+}
+
+// SplitEntities splits text and its entities into parts of at most limit
+// UTF-16 code units. Parts don't end inside an entity unless the entity is
+// longer than limit, in which case it is split as well.
+func SplitEntities(text string, entities []MessageEntity, limit int) []TextPart {
+	return splitEntities(text, entities, limit, limit)
+}
+
+// SplitHTML splits text formatted for ModeHTML into parts of at most limit
+// visible UTF-16 code units. Tags open at the end of a part are closed and
+// opened again at the start of the next one.
+func SplitHTML(text string, limit int) []string {
+	return splitHTML(text, limit, limit)
+}
+
+// SplitMarkdown splits text formatted for ModeMarkdown or ModeMarkdownV2
+// into parts of at most limit visible UTF-16 code units. Formatting open at
+// the end of a part is closed and opened again at the start of the next
+// one, and escaped characters are never split from their backslash.
+func SplitMarkdown(text, parseMode string, limit int) []string {
+	return splitMarkdown(text, parseMode == ModeMarkdownV2, limit, limit)
+}
+
+// SendLong sends a message of any length, split into as many messages as
+// needed. Every part after the first is sent as a reply to the previous
+// one, and only the last part has the reply markup. Formatting spanning a
+// split is closed and opened again around it.
+func (bot *BotAPI) SendLong(config MessageConfig) ([]Message, error) {
+	return bot.SendLongWithContext(context.Background(), config)
+}
+
+// SendLongWithContext is SendLong with a context.
+//
+// It returns the messages sent so far along with an error.
+func (bot *BotAPI) SendLongWithContext(ctx context.Context, config MessageConfig) ([]Message, error) {
+	parts := splitMessage(config.Text, config.ParseMode, config.Entities, MaxMessageTextLength, MaxMessageTextLength)
+
+	return bot.sendParts(ctx, config, parts, 0)
+}
+
+// SendLongCaption sends a photo, audio, document, video or voice with a
+// caption of any length. If the caption is longer than MaxCaptionLength,
+// the file carries its first part and the rest follows in messages sent
+// like SendLong, the first of them as a reply to the file. As with
+// SendLong, only the last message has the reply markup.
+func (bot *BotAPI) SendLongCaption(config Fileable) ([]Message, error) {
+	return bot.SendLongCaptionWithContext(context.Background(), config)
+}
+
+// SendLongCaptionWithContext is SendLongCaption with a context.
+func (bot *BotAPI) SendLongCaptionWithContext(ctx context.Context, config Fileable) ([]Message, error) {
+	caption, base, withCaption := fileCaption(config)
+	if withCaption == nil {
+		return nil, errors.New(ErrBadFileType)
+	}
+
+	parts := splitMessage(caption, "", nil, MaxCaptionLength, MaxMessageTextLength)
+
+	markup := base.ReplyMarkup
+	if len(parts) > 1 {
+		markup = nil
+	}
+	message, err := bot.SendWithContext(ctx, withCaption(parts[0].Text, markup))
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 1 {
+		return []Message{message}, nil
+	}
+
+	rest := MessageConfig{BaseChat: base}
+	messages, err := bot.sendParts(ctx, rest, parts[1:], message.MessageID)
+
+	return append([]Message{message}, messages...), err
+}
+
+// sendParts sends each part as a message based on config, threading them
+// as replies. replyTo, if set, is the message the first part replies to.
+func (bot *BotAPI) sendParts(ctx context.Context, config MessageConfig, parts []TextPart, replyTo int) ([]Message, error) {
+	messages := make([]Message, 0, len(parts))
+	for i, part := range parts {
+		c := config
+		c.Text = part.Text
+		c.Entities = part.Entities
+		if i < len(parts)-1 {
+			c.ReplyMarkup = nil
+		}
+		if i > 0 {
+			c.ReplyToMessageID = messages[i-1].MessageID
+		} else if replyTo != 0 {
+			c.ReplyToMessageID = replyTo
+		}
+
+		message, err := bot.SendWithContext(ctx, c)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// fileCaption returns the caption of a config along with a function
+// returning a copy of the config with another caption and reply markup.
+// withCaption is nil for configs without a caption.
+func fileCaption(config Fileable) (caption string, base BaseChat, withCaption func(string, interface{}) Fileable) {
+	switch c := config.(type) {
+	case PhotoConfig:
+		return c.Caption, c.BaseChat, func(s string, m interface{}) Fileable { c.Caption, c.ReplyMarkup = s, m; return c }
+	case AudioConfig:
+		return c.Caption, c.BaseChat, func(s string, m interface{}) Fileable { c.Caption, c.ReplyMarkup = s, m; return c }
+	case DocumentConfig:
+		return c.Caption, c.BaseChat, func(s string, m interface{}) Fileable { c.Caption, c.ReplyMarkup = s, m; return c }
+	case VideoConfig:
+		return c.Caption, c.BaseChat, func(s string, m interface{}) Fileable { c.Caption, c.ReplyMarkup = s, m; return c }
+	case VoiceConfig:
+		return c.Caption, c.BaseChat, func(s string, m interface{}) Fileable { c.Caption, c.ReplyMarkup = s, m; return c }
+	default:
+		return "", BaseChat{}, nil
+	}
+}
+
+// splitMessage splits text according to its parse mode. The first part
+// is at most firstLimit long, the others at most limit.
+func splitMessage(text, parseMode string, entities []MessageEntity, firstLimit, limit int) []TextPart {
+	var parts []TextPart
+	switch parseMode {
+	case ModeHTML:
+		for _, s := range splitHTML(text, firstLimit, limit) {
+			parts = append(parts, TextPart{Text: s})
+		}
+	case ModeMarkdown, ModeMarkdownV2:
+		for _, s := range splitMarkdown(text, parseMode == ModeMarkdownV2, firstLimit, limit) {
+			parts = append(parts, TextPart{Text: s})
+		}
+	default:
+		parts = splitEntities(text, entities, firstLimit, limit)
+	}
+
+	if len(parts) == 0 {
+		parts = append(parts, TextPart{Text: text, Entities: entities})
+	}
+
+	return parts
+}
+
+// splitPlain splits plain text.
+func splitPlain(text string, firstLimit, limit int) []string {
+	units := runeUnits(text)
+
+	var parts []string
+	for _, r := range splitUnits(units, firstLimit, limit, nil) {
+		parts = append(parts, joinUnits(units[r[0]:r[1]]))
+	}
+
+	return parts
+}
+
+// splitMarkdown splits Markdown text, closing and reopening formatting
+// around splits. v2 selects ModeMarkdownV2 over ModeMarkdown.
+func splitMarkdown(text string, v2 bool, firstLimit, limit int) []string {
+	units, open := markdownUnits(text, v2)
+	return splitMarkup(units, open, firstLimit, limit)
+}
+
+// splitEntities splits text along with its entities.
+func splitEntities(text string, entities []MessageEntity, firstLimit, limit int) []TextPart {
+	units := runeUnits(text)
+	offsets := unitOffsets(units)
+
+	outside := func(i int) bool {
+		for _, entity := range entities {
+			if entity.Offset < offsets[i] && offsets[i] < entity.Offset+entity.Length {
+				return false
+			}
+		}
+		return true
+	}
+
+	var parts []TextPart
+	for _, r := range splitUnits(units, firstLimit, limit, outside) {
+		start, end := offsets[r[0]], offsets[r[1]]
+
+		part := TextPart{Text: joinUnits(units[r[0]:r[1]])}
+		for _, entity := range entities {
+			from, to := entity.Offset, entity.Offset+entity.Length
+			if from < start {
+				from = start
+			}
+			if to > end {
+				to = end
+			}
+			if from < to {
+				entity.Offset = from - start
+				entity.Length = to - from
+				part.Entities = append(part.Entities, entity)
+			}
+		}
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// splitHTML splits HTML text, closing and reopening tags around splits.
+func splitHTML(text string, firstLimit, limit int) []string {
+	units := htmlUnits(text)
+
+	// open[i] holds the tags open before unit i.
+	open := make([][]markup, len(units)+1)
+	var stack []markup
+	for i, unit := range units {
+		open[i] = stack
+		if unit.char == 0 {
+			stack = applyTag(stack, unit.raw)
+		}
+	}
+	open[len(units)] = stack
+
+	return splitMarkup(units, open, firstLimit, limit)
+}
+
+// splitMarkup splits units, given the markup open before each of them and
+// at the end, preferring splits outside any markup. Markup open across a
+// split is closed at the end of a part and opened again in the next one.
+func splitMarkup(units []textUnit, open [][]markup, firstLimit, limit int) []string {
+	closed := func(i int) bool {
+		return len(open[i]) == 0
+	}
+
+	var parts []string
+	for _, r := range splitUnits(units, firstLimit, limit, closed) {
+		var b strings.Builder
+		for _, m := range open[r[0]] {
+			b.WriteString(m.open)
+		}
+		b.WriteString(joinUnits(units[r[0]:r[1]]))
+		stack := open[r[1]]
+		for i := len(stack) - 1; i >= 0; i-- {
+			b.WriteString(stack[i].close)
+		}
+		parts = append(parts, b.String())
+	}
+
+	return parts
+}
+
+// splitUnits returns the ranges of units forming each part. It prefers to
+// end parts after a separator at a unit where ok is true, then at any unit
+// where ok is true, and cuts at the limit otherwise. Whitespace around the
+// splits is dropped.
+func splitUnits(units []textUnit, firstLimit, limit int, ok func(i int) bool) [][2]int {
+	if ok == nil {
+		ok = func(int) bool { return true }
+	}
+
+	var ranges [][2]int
+	start := 0
+	for start < len(units) {
+		max := limit
+		if len(ranges) == 0 {
+			max = firstLimit
+		}
+
+		end, width := start, 0
+		for end < len(units) && width+units[end].width <= max {
+			width += units[end].width
+			end++
+		}
+		if end == start {
+			end++
+		}
+
+		if end < len(units) {
+			end = findBreak(units, start, end, ok)
+		}
+
+		partEnd := end
+		for partEnd > start && isSpaceUnit(units[partEnd-1]) {
+			partEnd--
+		}
+		if partEnd > start {
+			ranges = append(ranges, [2]int{start, partEnd})
+		}
+
+		start = end
+		for start < len(units) && isSpaceUnit(units[start]) {
+			start++
+		}
+	}
+
+	return ranges
+}
+
+// findBreak returns where to end a part starting at start which may extend
+// up to max units. It prefers separators at units where ok is true, then
+// separators anywhere, then any unit where ok is true.
+func findBreak(units []textUnit, start, max int, ok func(i int) bool) int {
+	always := func(int) bool { return true }
+	for _, accept := range []func(int) bool{ok, always} {
+		for _, sep := range splitSeparators {
+			for end := max; end > start; end-- {
+				if accept(end) && endsWith(units[start:end], sep) {
+					return end
+				}
+			}
+		}
+	}
+
+	for end := max; end > start; end-- {
+		if ok(end) {
+			return end
+		}
+	}
+
+	return max
+}
+
+// endsWith returns if the characters of units end with sep, ignoring
+// HTML tags.
+func endsWith(units []textUnit, sep string) bool {
+	i := len(units)
+	for j := len(sep); j > 0; {
+		r, size := utf8.DecodeLastRuneInString(sep[:j])
+		for i > 0 && units[i-1].char == 0 {
+			i--
+		}
+		if i == 0 || units[i-1].char != r {
+			return false
+		}
+		i--
+		j -= size
+	}
+
+	return true
+}
+
+// isSpaceUnit returns if unit is a space or a line break.
+func isSpaceUnit(unit textUnit) bool {
+	return unit.char == ' ' || unit.char == '\n'
+}
+
+// runeUnits splits plain text into its runes.
+func runeUnits(text string) []textUnit {
+	units := make([]textUnit, 0, len(text))
+	for _, r := range text {
+		units = append(units, textUnit{raw: string(r), char: r, width: utf16RuneLen(r)})
+	}
+
+	return units
+}
+
+// htmlUnits splits HTML text into tags, character references and runes.
+func htmlUnits(text string) []textUnit {
+	var units []textUnit
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if end := strings.IndexByte(text[i:], '>'); end != -1 {
+				units = append(units, textUnit{raw: text[i : i+end+1]})
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(text[i:], ';'); end > 1 && end <= 10 {
+				units = append(units, textUnit{raw: text[i : i+end+1], char: '&', width: 1})
+				i += end + 1
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		units = append(units, textUnit{raw: text[i : i+size], char: r, width: utf16RuneLen(r)})
+		i += size
+	}
+
+	return units
+}
+
+// unitOffsets returns the UTF-16 offset of every unit, and of the end.
+func unitOffsets(units []textUnit) []int {
+	offsets := make([]int, len(units)+1)
+	for i, unit := range units {
+		offsets[i+1] = offsets[i] + unit.width
+	}
+
+	return offsets
+}
+
+// joinUnits returns the raw text of units.
+func joinUnits(units []textUnit) string {
+	var b strings.Builder
+	for _, unit := range units {
+		b.WriteString(unit.raw)
+	}
+
+	return b.String()
+}
+
+// voidTags are the HTML elements without a closing tag.
+var voidTags = map[string]bool{
+	"br": true, "hr": true, "img": true, "input": true, "meta": true, "wbr": true,
+}
+
+// applyTag returns the stack of open tags after tag. Self-closing and void
+// tags leave it unchanged.
+func applyTag(stack []markup, tag string) []markup {
+	name := tagName(tag)
+	if strings.HasSuffix(tag, "/>") || voidTags[name] {
+		return stack
+	}
+	closing := "</" + name + ">"
+	if !strings.HasPrefix(tag, "</") {
+		return append(stack[:len(stack):len(stack)], markup{open: tag, close: closing})
+	}
+
+	return closeMarkup(stack, closing)
+}
+
+// closeMarkup returns stack without the innermost markup closed by close,
+// or stack itself if there is none.
+func closeMarkup(stack []markup, close string) []markup {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].close == close {
+			return append(stack[:i:i], stack[i+1:]...)
+		}
+	}
+
+	return stack
+}
+
+// markdownMarkers are the markers toggling formatting outside code, longest
+// first.
+var markdownMarkers = map[bool][]string{
+	false: {"*", "_"},
+	true:  {"||", "__", "*", "_", "~"},
+}
+
+// markdownUnits splits Markdown text into markers, escaped characters and
+// runes, and returns the formatting open before each unit and at the end.
+// Code and pre blocks and links are closed by their own syntax; other
+// markers close the innermost formatting they opened, or open a new one.
+func markdownUnits(text string, v2 bool) ([]textUnit, [][]markup) {
+	var units []textUnit
+	var open [][]markup
+	var stack []markup
+
+	add := func(unit textUnit) {
+		open = append(open, stack)
+		units = append(units, unit)
+	}
+	// literal adds the runes of code, where only backslashes escape in
+	// ModeMarkdownV2.
+	literal := func(code string) {
+		for i := 0; i < len(code); {
+			start := i
+			if v2 && code[i] == '\\' && i+1 < len(code) {
+				i++
+			}
+			r, size := utf8.DecodeRuneInString(code[i:])
+			i += size
+			add(textUnit{raw: code[start:i], char: r, width: utf16RuneLen(r)})
+		}
+	}
+
+	// linkEnds maps the offset of the "](url)" closing a link to its end.
+	linkEnds := make(map[int]int)
+
+	for i := 0; i < len(text); {
+		if end, ok := linkEnds[i]; ok {
+			add(textUnit{raw: text[i:end]})
+			stack = closeMarkup(stack, text[i:end])
+			i = end
+			continue
+		}
+
+		switch rest := text[i:]; {
+		case rest[0] == '\\' && len(rest) > 1:
+			r, size := utf8.DecodeRuneInString(rest[1:])
+			add(textUnit{raw: rest[:1+size], char: r, width: utf16RuneLen(r)})
+			i += 1 + size
+			continue
+		case strings.HasPrefix(rest, "```"):
+			if end := strings.Index(rest[3:], "```"); end != -1 {
+				opener := "```"
+				if nl := strings.IndexByte(rest[3:3+end], '\n'); nl != -1 {
+					opener = rest[:3+nl+1]
+				}
+				add(textUnit{raw: opener})
+				stack = append(stack[:len(stack):len(stack)], markup{open: opener, close: "```"})
+				literal(rest[len(opener) : 3+end])
+				add(textUnit{raw: "```"})
+				stack = closeMarkup(stack, "```")
+				i += 3 + end + 3
+				continue
+			}
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end != -1 {
+				add(textUnit{raw: "`"})
+				stack = append(stack[:len(stack):len(stack)], markup{open: "`", close: "`"})
+				literal(rest[1 : 1+end])
+				add(textUnit{raw: "`"})
+				stack = closeMarkup(stack, "`")
+				i += 1 + end + 1
+				continue
+			}
+		case rest[0] == '[':
+			if mid := strings.Index(rest, "]("); mid != -1 {
+				if end := strings.IndexByte(rest[mid:], ')'); end != -1 {
+					linkEnds[i+mid] = i + mid + end + 1
+					add(textUnit{raw: "["})
+					stack = append(stack[:len(stack):len(stack)], markup{open: "[", close: rest[mid : mid+end+1]})
+					i++
+					continue
+				}
+			}
+		}
+
+		marker := ""
+		for _, m := range markdownMarkers[v2] {
+			if strings.HasPrefix(text[i:], m) {
+				marker = m
+				break
+			}
+		}
+		if marker != "" {
+			add(textUnit{raw: marker})
+			if closed := closeMarkup(stack, marker); len(closed) != len(stack) {
+				stack = closed
+			} else {
+				stack = append(stack[:len(stack):len(stack)], markup{open: marker, close: marker})
+			}
+			i += len(marker)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		add(textUnit{raw: text[i : i+size], char: r, width: utf16RuneLen(r)})
+		i += size
+	}
+	open = append(open, stack)
+
+	return units, open
+}
+
+// tagName returns the lowercased name of an HTML tag.
+func tagName(tag string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(tag, "<"), "/")
+	if i := strings.IndexAny(name, " \t\n>/"); i != -1 {
+		name = name[:i]
+	}
+
+	return strings.ToLower(name)
+}
@@ -0,0 +1,73 @@
+package tgbotapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitText(t *testing.T) {
+	text := "first paragraph\n\nsecond line\nthird words here"
+
+	assert.Equal(t, []string{text}, SplitText(text, 100))
+	assert.Equal(t, []string{"first paragraph", "second line", "third words here"}, SplitText(text, 20))
+	assert.Equal(t, []string{"abc", "def", "g"}, SplitText("abcdefg", 3))
+
+	for _, part := range SplitText(strings.Repeat("😀", 5), 4) {
+		assert.True(t, UTF16Len(part) <= 4)
+	}
+}
+
+func TestSplitEntities(t *testing.T) {
+	text := "aa bb cc dd"
+	parts := SplitEntities(text, []MessageEntity{
+		{Type: "bold", Offset: 3, Length: 5},
+		{Type: "italic", Offset: 9, Length: 2},
+	}, 7)
+
+	assert.Equal(t, []TextPart{
+		{Text: "aa"},
+		{Text: "bb cc", Entities: []MessageEntity{{Type: "bold", Offset: 0, Length: 5}}},
+		{Text: "dd", Entities: []MessageEntity{{Type: "italic", Offset: 0, Length: 2}}},
+	}, parts)
+
+	parts = SplitEntities("abcdef", []MessageEntity{{Type: "code", Offset: 0, Length: 6}}, 4)
+	assert.Equal(t, []TextPart{
+		{Text: "abcd", Entities: []MessageEntity{{Type: "code", Offset: 0, Length: 4}}},
+		{Text: "ef", Entities: []MessageEntity{{Type: "code", Offset: 0, Length: 2}}},
+	}, parts)
+}
+
+func TestSplitHTML(t *testing.T) {
+	parts := SplitHTML(`<b>one two</b> <a href="https://example.com">three &amp; four</a>`, 10)
+
+	assert.Equal(t, []string{
+		"<b>one two</b>",
+		`<a href="https://example.com">three &amp;</a>`,
+		`<a href="https://example.com">four</a>`,
+	}, parts)
+}
+
+func TestSplitHTMLSelfClosingTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "<br/>b"}, SplitHTML("a <br/>b", 1))
+	assert.Equal(t, []string{"<i>a</i>", "<i><br>b</i>"}, SplitHTML("<i>a <br>b</i>", 1))
+}
+
+func TestSplitMarkdownEscapes(t *testing.T) {
+	parts := splitMessage(`ab\_cd`, ModeMarkdownV2, nil, 3, 3)
+	assert.Equal(t, []TextPart{{Text: `ab\_`}, {Text: "cd"}}, parts)
+
+	parts = splitMessage(`a\\\_b`, ModeMarkdownV2, nil, 2, 2)
+	assert.Equal(t, []TextPart{{Text: `a\\`}, {Text: `\_b`}}, parts)
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	assert.Equal(t, []string{"*one*", "*two*"}, SplitMarkdown("*one two*", ModeMarkdown, 4))
+	assert.Equal(t, []string{"a", "*b _c_*", "*_d_ e*"}, SplitMarkdown("a *b _c d_ e*", ModeMarkdownV2, 4))
+	assert.Equal(t, []string{"`a`", "`b c`"}, SplitMarkdown("`a b c`", ModeMarkdownV2, 3))
+	assert.Equal(t, []string{"```go\nx := 1```", "```go\ny```"}, SplitMarkdown("```go\nx := 1\ny```", ModeMarkdownV2, 7))
+	assert.Equal(t, []string{"[one](https://example.com)", "[two](https://example.com)"}, SplitMarkdown("[one two](https://example.com)", ModeMarkdownV2, 4))
+	assert.Equal(t, []string{"||a||", "||b||"}, SplitMarkdown("||a b||", ModeMarkdownV2, 1))
+	assert.Equal(t, []string{`a\*`, "b"}, SplitMarkdown(`a\* b`, ModeMarkdownV2, 2))
+}
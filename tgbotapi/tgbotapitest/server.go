@@ -0,0 +1,582 @@
+// Package tgbotapitest provides a fake Telegram Bot API server for testing
+// bots built with tgbotapi.
+package tgbotapitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byepp/util/tgbotapi"
+)
+
+// DefaultToken is the bot token accepted by a Server.
+const DefaultToken = "123456:TEST-TOKEN"
+
+// maxPollWait caps how long getUpdates waits for new updates, so that
+// tests do not hang on a long timeout.
+const maxPollWait = 5 * time.Second
+
+// Call is a request received by a Server.
+type Call struct {
+	Method string
+	Params url.Values
+	// Files holds the content of uploaded files by field name.
+	Files map[string][]byte
+}
+
+// HandlerFunc answers a call to an API method. The result is encoded as
+// the result field of the response, a non nil error is sent as a failed
+// response.
+type HandlerFunc func(call Call) (interface{}, *tgbotapi.Error)
+
+// Server is an in-process fake of the Telegram Bot API.
+//
+// It answers the methods used by BotAPI with plausible results, records
+// every call, and lets tests inject updates and errors. Methods it does
+// not know succeed with a true result.
+type Server struct {
+	Token string
+	Self  tgbotapi.User
+
+	server *httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	failures      map[string][]*tgbotapi.Error
+	handlers      map[string]HandlerFunc
+	commands      map[string][]tgbotapi.BotCommand
+	files         map[string][]byte
+	pushed        chan struct{}
+}
+
+// NewServer starts a new Server. It should be closed when the test is done.
+func NewServer() *Server {
+	s := &Server{
+		Token:         DefaultToken,
+		Self:          tgbotapi.User{ID: 123456, FirstName: "Test", UserName: "test_bot", IsBot: true},
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		failures:      make(map[string][]*tgbotapi.Error),
+		handlers:      make(map[string]HandlerFunc),
+		commands:      make(map[string][]tgbotapi.BotCommand),
+		files:         make(map[string][]byte),
+		pushed:        make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// APIEndpoint returns the endpoint to pass to NewBotAPIWithAPIEndpoint.
+func (s *Server) APIEndpoint() string {
+	return s.server.URL + "/bot%s/%s"
+}
+
+// FileEndpoint returns the file endpoint of the server, to be used with
+// BotAPI.SetFileEndpoint.
+func (s *Server) FileEndpoint() string {
+	return s.server.URL + "/file/bot%s/%s"
+}
+
+// AddFile stores the content of a file, so that getFile reports its size
+// and downloads return it. Range requests are supported.
+func (s *Server) AddFile(fileID string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[fileID] = content
+}
+
+// NewBot creates a BotAPI talking to the server. Its Limiter is removed so
+// that tests are not slowed down by throttling.
+func (s *Server) NewBot() (*tgbotapi.BotAPI, error) {
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(s.Token, s.APIEndpoint(), s.server.Client())
+	if err != nil {
+		return nil, err
+	}
+	bot.SetFileEndpoint(s.FileEndpoint())
+	bot.Limiter = nil
+
+	return bot, nil
+}
+
+// Calls returns every call received so far.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo returns the calls received so far to an API method.
+func (s *Server) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range s.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// PushUpdate queues an update for getUpdates. An UpdateID is assigned if
+// it is zero. It returns the queued update.
+func (s *Server) PushUpdate(update tgbotapi.Update) tgbotapi.Update {
+	s.mu.Lock()
+	if update.UpdateID == 0 {
+		update.UpdateID = s.nextUpdateID
+	}
+	if update.UpdateID >= s.nextUpdateID {
+		s.nextUpdateID = update.UpdateID + 1
+	}
+	s.updates = append(s.updates, update)
+
+	close(s.pushed)
+	s.pushed = make(chan struct{})
+	s.mu.Unlock()
+
+	return update
+}
+
+// PushMessage queues an update with a text message sent by a user in a
+// private chat. Texts starting with a slash are marked as commands.
+func (s *Server) PushMessage(chatID int64, text string) tgbotapi.Update {
+	user := &tgbotapi.User{ID: int(chatID), FirstName: "User"}
+	message := &tgbotapi.Message{
+		MessageID: s.newMessageID(),
+		From:      user,
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private", FirstName: user.FirstName},
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		length := len(text)
+		if i := strings.IndexAny(text, " \n"); i != -1 {
+			length = i
+		}
+		message.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+
+	return s.PushUpdate(tgbotapi.Update{Message: message})
+}
+
+// Fail makes the next call to the method fail with err. Several failures
+// for the same method are used in order.
+func (s *Server) Fail(method string, err *tgbotapi.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = append(s.failures[method], err)
+}
+
+// FailTooManyRequests makes the next call to the method hit flood control,
+// asking to retry after the given number of seconds.
+func (s *Server) FailTooManyRequests(method string, retryAfter int) {
+	s.Fail(method, &tgbotapi.Error{
+		Code:               http.StatusTooManyRequests,
+		Message:            "Too Many Requests: retry after " + strconv.Itoa(retryAfter),
+		ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: retryAfter},
+	})
+}
+
+// Handle replaces the way the server answers an API method.
+func (s *Server) Handle(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[method] = handler
+}
+
+// serveHTTP routes a request to the handler of its method.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/file/") {
+		s.serveFile(w, r)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	i := strings.LastIndex(path, "/")
+	if i == -1 || path[:i] != s.Token {
+		writeResponse(w, nil, &tgbotapi.Error{Code: http.StatusUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	call, err := readCall(r, path[i+1:])
+	if err != nil {
+		writeResponse(w, nil, &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: " + err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	var failure *tgbotapi.Error
+	if failures := s.failures[call.Method]; len(failures) != 0 {
+		failure = failures[0]
+		s.failures[call.Method] = failures[1:]
+	}
+	handler, ok := s.handlers[call.Method]
+	s.mu.Unlock()
+
+	if failure != nil {
+		writeResponse(w, nil, failure)
+		return
+	}
+
+	if !ok {
+		handler = s.defaultHandler(r, call.Method)
+	}
+
+	result, apiErr := handler(call)
+	writeResponse(w, result, apiErr)
+}
+
+// serveFile serves the content of a file added with AddFile.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	prefix := "/file/bot" + s.Token + "/files/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeResponse(w, nil, &tgbotapi.Error{Code: http.StatusUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	fileID := strings.TrimPrefix(r.URL.Path, prefix)
+	s.mu.Lock()
+	content, ok := s.files[fileID]
+	s.mu.Unlock()
+	if !ok {
+		writeResponse(w, nil, &tgbotapi.Error{Code: http.StatusNotFound, Message: "Not Found"})
+		return
+	}
+
+	http.ServeContent(w, r, fileID, time.Time{}, bytes.NewReader(content))
+}
+
+// defaultHandler returns the built-in handler of an API method.
+func (s *Server) defaultHandler(r *http.Request, method string) HandlerFunc {
+	switch {
+	case method == "getMe":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return s.Self, nil
+		}
+	case method == "getUpdates":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return s.getUpdates(r, call), nil
+		}
+	case method == "sendMediaGroup":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			var media []json.RawMessage
+			if err := json.Unmarshal([]byte(call.Params.Get("media")), &media); err != nil {
+				return nil, &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: can't parse media"}
+			}
+
+			messages := make([]tgbotapi.Message, len(media))
+			for i := range media {
+				messages[i] = s.message(call)
+				messages[i].MediaGroupID = "album-" + strconv.Itoa(messages[0].MessageID)
+			}
+			return messages, nil
+		}
+	case method == "stopPoll":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return tgbotapi.Poll{ID: "poll-" + call.Params.Get("message_id"), IsClosed: true}, nil
+		}
+	case method == "createForumTopic":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			iconColor, _ := strconv.Atoi(call.Params.Get("icon_color"))
+			return tgbotapi.ForumTopic{
+				MessageThreadID:   s.newMessageID(),
+				Name:              call.Params.Get("name"),
+				IconColor:         iconColor,
+				IconCustomEmojiID: call.Params.Get("icon_custom_emoji_id"),
+			}, nil
+		}
+	case method == "setMyCommands":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			var commands []tgbotapi.BotCommand
+			if err := json.Unmarshal([]byte(call.Params.Get("commands")), &commands); err != nil {
+				return nil, &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: can't parse commands"}
+			}
+
+			s.mu.Lock()
+			s.commands[commandsKey(call)] = commands
+			s.mu.Unlock()
+			return true, nil
+		}
+	case method == "getMyCommands":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			commands := s.commands[commandsKey(call)]
+			if commands == nil {
+				commands = []tgbotapi.BotCommand{}
+			}
+			return commands, nil
+		}
+	case method == "deleteMyCommands":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			s.mu.Lock()
+			delete(s.commands, commandsKey(call))
+			s.mu.Unlock()
+			return true, nil
+		}
+	case method == "createInvoiceLink":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return "https://t.me/$" + call.Params.Get("payload"), nil
+		}
+	case method == "getChatAdministrators":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return []tgbotapi.ChatMember{{User: &s.Self, Status: "administrator"}}, nil
+		}
+	case method == "getFile":
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			fileID := call.Params.Get("file_id")
+			s.mu.Lock()
+			size := len(s.files[fileID])
+			s.mu.Unlock()
+			return tgbotapi.File{FileID: fileID, FileSize: size, FilePath: "files/" + fileID}, nil
+		}
+	case strings.HasPrefix(method, "send") && method != "sendChatAction",
+		method == "forwardMessage",
+		strings.HasPrefix(method, "edit"):
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			if call.Params.Get("inline_message_id") != "" {
+				return true, nil
+			}
+			return s.message(call), nil
+		}
+	default:
+		return func(call Call) (interface{}, *tgbotapi.Error) {
+			return true, nil
+		}
+	}
+}
+
+// commandsKey returns the key of the command list of the scope and
+// language of a call.
+func commandsKey(call Call) string {
+	var scope tgbotapi.BotCommandScope
+	json.Unmarshal([]byte(call.Params.Get("scope")), &scope)
+	if scope.Type == "" {
+		scope.Type = tgbotapi.BotCommandScopeTypeDefault
+	}
+
+	data, _ := json.Marshal(scope)
+	return string(data) + "|" + call.Params.Get("language_code")
+}
+
+// getUpdates returns the queued updates from the requested offset. If
+// there are none it waits for one to be pushed, up to the timeout.
+func (s *Server) getUpdates(r *http.Request, call Call) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(call.Params.Get("offset"))
+	timeout, _ := strconv.Atoi(call.Params.Get("timeout"))
+
+	wait := time.Duration(timeout) * time.Second
+	if wait > maxPollWait {
+		wait = maxPollWait
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		updates := []tgbotapi.Update{}
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				updates = append(updates, update)
+			}
+		}
+		pushed := s.pushed
+		s.mu.Unlock()
+
+		if len(updates) != 0 || wait == 0 {
+			return updates
+		}
+
+		select {
+		case <-pushed:
+		case <-deadline.C:
+			return updates
+		case <-r.Context().Done():
+			return updates
+		}
+	}
+}
+
+// message builds the Message a send or edit call results in.
+func (s *Server) message(call Call) tgbotapi.Message {
+	chat := &tgbotapi.Chat{Type: "private"}
+	chatID := call.Params.Get("chat_id")
+	if id, err := strconv.ParseInt(chatID, 10, 64); err == nil {
+		chat.ID = id
+		if id < 0 {
+			chat.Type = "supergroup"
+		}
+	} else {
+		chat.UserName = strings.TrimPrefix(chatID, "@")
+		chat.Type = "channel"
+	}
+
+	messageID, _ := strconv.Atoi(call.Params.Get("message_id"))
+	if messageID == 0 || !strings.HasPrefix(call.Method, "edit") {
+		messageID = s.newMessageID()
+	}
+
+	self := s.Self
+	message := tgbotapi.Message{
+		MessageID: messageID,
+		From:      &self,
+		Date:      int(time.Now().Unix()),
+		Chat:      chat,
+		Text:      call.Params.Get("text"),
+		Caption:   call.Params.Get("caption"),
+	}
+	if strings.HasPrefix(call.Method, "edit") {
+		message.EditDate = message.Date
+	}
+	if threadID, err := strconv.Atoi(call.Params.Get("message_thread_id")); err == nil {
+		chat.IsForum = true
+		message.MessageThreadID = threadID
+		message.IsTopicMessage = true
+	}
+
+	switch call.Method {
+	case "sendPoll":
+		poll := &tgbotapi.Poll{
+			ID:          "poll-" + strconv.Itoa(messageID),
+			Question:    call.Params.Get("question"),
+			IsAnonymous: call.Params.Get("is_anonymous") != "false",
+			Type:        call.Params.Get("type"),
+		}
+		var options []string
+		json.Unmarshal([]byte(call.Params.Get("options")), &options)
+		for _, option := range options {
+			poll.Options = append(poll.Options, tgbotapi.PollOption{Text: option})
+		}
+		message.Poll = poll
+	case "sendDice":
+		emoji := call.Params.Get("emoji")
+		if emoji == "" {
+			emoji = tgbotapi.DiceEmoji
+		}
+		message.Dice = &tgbotapi.Dice{Emoji: emoji, Value: 1}
+	}
+
+	return message
+}
+
+// newMessageID returns the next message ID.
+func (s *Server) newMessageID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextMessageID
+	s.nextMessageID++
+
+	return id
+}
+
+// readCall reads the parameters and files of a request. Fields of a JSON
+// body are read as parameters, objects and arrays as their JSON encoding.
+func readCall(r *http.Request, method string) (Call, error) {
+	call := Call{Method: method, Params: url.Values{}, Files: map[string][]byte{}}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return call, err
+		}
+
+		for key, values := range r.MultipartForm.Value {
+			call.Params[key] = values
+		}
+		for key, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return call, err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return call, err
+			}
+			call.Files[key] = data
+		}
+
+		return call, nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return call, err
+		}
+
+		for key, raw := range body {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				value = string(raw)
+			}
+			call.Params.Set(key, value)
+		}
+
+		return call, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return call, err
+	}
+	for key, values := range r.PostForm {
+		call.Params[key] = values
+	}
+
+	return call, nil
+}
+
+// writeResponse writes an API response with the result or the error.
+func writeResponse(w http.ResponseWriter, result interface{}, apiErr *tgbotapi.Error) {
+	resp := tgbotapi.APIResponse{Ok: apiErr == nil}
+
+	if apiErr != nil {
+		resp.ErrorCode = apiErr.Code
+		resp.Description = apiErr.Message
+		if apiErr.ResponseParameters != (tgbotapi.ResponseParameters{}) {
+			parameters := apiErr.ResponseParameters
+			resp.Parameters = &parameters
+		}
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Result = data
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if apiErr != nil {
+		w.WriteHeader(apiErr.Code)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,1104 @@
+package tgbotapitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/byepp/util/tgbotapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBot(t *testing.T) (*Server, *tgbotapi.BotAPI) {
+	s := NewServer()
+	t.Cleanup(s.Close)
+
+	bot, err := s.NewBot()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return s, bot
+}
+
+func TestSend(t *testing.T) {
+	s, bot := newBot(t)
+	assert.Equal(t, "test_bot", bot.Self.UserName)
+
+	message, err := bot.Send(tgbotapi.NewMessage(42, "hello"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(42), message.Chat.ID)
+	assert.Equal(t, "hello", message.Text)
+
+	photo, err := bot.Send(tgbotapi.NewPhotoUpload(42, tgbotapi.FileBytes{Name: "a.png", Bytes: []byte("png")}))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotEqual(t, message.MessageID, photo.MessageID)
+
+	calls := s.CallsTo("sendPhoto")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, []byte("png"), calls[0].Files["photo"])
+		assert.Equal(t, "42", calls[0].Params.Get("chat_id"))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s, bot := newBot(t)
+
+	s.Fail("sendMessage", &tgbotapi.Error{Code: http.StatusForbidden, Message: "Forbidden: bot was blocked by the user"})
+	_, err := bot.Send(tgbotapi.NewMessage(42, "hello"))
+	assert.True(t, tgbotapi.IsForbidden(err))
+
+	s.FailTooManyRequests("sendMessage", 1)
+	start := time.Now()
+	_, err = bot.Send(tgbotapi.NewMessage(42, "hello"))
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= time.Second)
+	assert.Len(t, s.CallsTo("sendMessage"), 3)
+}
+
+func TestChatMigration(t *testing.T) {
+	s, bot := newBot(t)
+
+	var migrated int64
+	bot.OnChatMigrated = func(fromChatID, toChatID int64) {
+		migrated = toChatID
+	}
+
+	s.Fail("sendMessage", &tgbotapi.Error{
+		Code:               http.StatusBadRequest,
+		Message:            "Bad Request: group chat was upgraded to a supergroup chat",
+		ResponseParameters: tgbotapi.ResponseParameters{MigrateToChatID: -1001},
+	})
+
+	message, err := bot.Send(tgbotapi.NewMessage(-1, "hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1001), message.Chat.ID)
+	assert.Equal(t, int64(-1001), migrated)
+
+	s.Fail("sendDocument", &tgbotapi.Error{
+		Code:               http.StatusBadRequest,
+		Message:            "Bad Request: group chat was upgraded to a supergroup chat",
+		ResponseParameters: tgbotapi.ResponseParameters{MigrateToChatID: -1002},
+	})
+
+	params := map[string]string{"chat_id": "-2"}
+	_, err = bot.UploadFile("sendDocument", params, "document", tgbotapi.FileBytes{Name: "a.txt", Bytes: []byte("a")})
+	assert.Nil(t, err)
+	assert.Equal(t, "-2", params["chat_id"])
+	if calls := s.CallsTo("sendDocument"); assert.Len(t, calls, 2) {
+		assert.Equal(t, "-1002", calls[1].Params.Get("chat_id"))
+	}
+}
+
+func TestPolling(t *testing.T) {
+	s, bot := newBot(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+	poller := bot.StartPolling(ctx, config)
+
+	s.PushMessage(7, "/start")
+	update := <-poller.Updates()
+	assert.Equal(t, "start", update.Message.Command())
+
+	cancel()
+	select {
+	case <-poller.Done():
+	case <-time.After(time.Second):
+		t.Fatal("poller did not stop")
+	}
+
+	_, open := <-poller.Updates()
+	assert.False(t, open)
+	assert.Equal(t, update.UpdateID+1, poller.Offset())
+}
+
+func TestServerURL(t *testing.T) {
+	s, bot := newBot(t)
+
+	bot.SetServerURL("http://127.0.0.1:1")
+	_, err := bot.GetMe()
+	assert.NotNil(t, err)
+
+	bot.SetServerURL(s.URL() + "/")
+	_, err = bot.GetMe()
+	assert.Nil(t, err)
+
+	s.AddFile("doc", []byte("content"))
+	link, err := bot.GetFileDirectURL("doc")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(link, s.URL()+"/file/bot"+s.Token+"/"), link)
+
+	bot.SetAPIEndpoint("http://127.0.0.1:1/bot%s/%s")
+	_, err = bot.GetMe()
+	assert.NotNil(t, err)
+	bot.SetAPIEndpoint(s.APIEndpoint())
+	bot.SetFileEndpoint("https://files.example.com/%s/%s")
+	link, err = bot.GetFileDirectURL("doc")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(link, "https://files.example.com/"+s.Token+"/"), link)
+
+	s.Handle("getFile", func(call Call) (interface{}, *tgbotapi.Error) {
+		return tgbotapi.File{FileID: "local", FilePath: `C:\bots\doc.pdf`}, nil
+	})
+	link, err = bot.GetFileDirectURL("local")
+	assert.Nil(t, err)
+	assert.Equal(t, `C:\bots\doc.pdf`, link)
+
+	_, err = bot.LogOut()
+	assert.Nil(t, err)
+	_, err = bot.Close()
+	assert.Nil(t, err)
+	assert.Len(t, s.CallsTo("logOut"), 1)
+	assert.Len(t, s.CallsTo("close"), 1)
+}
+
+func TestRecentMessagesDelete(t *testing.T) {
+	s, bot := newBot(t)
+
+	recent := tgbotapi.NewRecentMessages()
+	recent.Limit = 0
+	record := func(id int) {
+		recent.Record(&tgbotapi.Message{MessageID: id, Chat: &tgbotapi.Chat{ID: -100}, From: &tgbotapi.User{ID: 7}})
+	}
+	for i := 1; i <= 150; i++ {
+		record(i)
+	}
+
+	var batches int32
+	s.Handle("deleteMessages", func(call Call) (interface{}, *tgbotapi.Error) {
+		batch := atomic.AddInt32(&batches, 1)
+		if batch == 2 {
+			return nil, &tgbotapi.Error{Code: http.StatusInternalServerError, Message: "Internal Server Error"}
+		}
+		record(150 + int(batch))
+		return true, nil
+	})
+
+	assert.NotNil(t, recent.Delete(bot, -100, 7))
+	ids := recent.IDs(-100, 7)
+	assert.Len(t, ids, 51)
+	assert.Equal(t, 101, ids[0])
+	assert.Equal(t, 151, ids[50])
+
+	assert.Nil(t, recent.Delete(bot, -100, 7))
+	assert.Equal(t, []int{153}, recent.IDs(-100, 7))
+}
+
+func TestConfigMethods(t *testing.T) {
+	s, bot := newBot(t)
+
+	s.Handle("getChat", func(call Call) (interface{}, *tgbotapi.Error) {
+		return tgbotapi.Chat{ID: -100, UserName: call.Params.Get("chat_id")}, nil
+	})
+	chat, err := bot.GetChat(tgbotapi.ChatConfig{SuperGroupUsername: "@group"})
+	assert.Nil(t, err)
+	assert.Equal(t, "@group", chat.UserName)
+
+	s.Fail("getChatMembersCount", &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: chat not found"})
+	count, err := bot.GetChatMembersCount(tgbotapi.ChatConfig{ChatID: 42})
+	assert.True(t, tgbotapi.IsChatNotFound(err))
+	assert.Equal(t, -1, count)
+
+	s.Handle("getChatMember", func(call Call) (interface{}, *tgbotapi.Error) {
+		return tgbotapi.ChatMember{Status: "member"}, nil
+	})
+	member, err := bot.GetChatMember(tgbotapi.ChatConfigWithUser{ChatID: 42, UserID: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, "member", member.Status)
+	assert.Equal(t, "7", s.CallsTo("getChatMember")[0].Params.Get("user_id"))
+
+	_, err = bot.AnswerCallbackQuery(tgbotapi.NewCallback("query", "done"))
+	assert.Nil(t, err)
+	call := s.CallsTo("answerCallbackQuery")[0]
+	assert.Equal(t, "query", call.Params.Get("callback_query_id"))
+	assert.Equal(t, "done", call.Params.Get("text"))
+
+	restrict := tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: 42, UserID: 7},
+		UntilDate:        100,
+		Permissions:      &tgbotapi.ChatPermissions{},
+	}
+	_, err = bot.RestrictChatMember(restrict)
+	assert.Nil(t, err)
+	call = s.CallsTo("restrictChatMember")[0]
+	assert.Equal(t, "42", call.Params.Get("chat_id"))
+	assert.Equal(t, "100", call.Params.Get("until_date"))
+	assert.NotEmpty(t, call.Params.Get("permissions"))
+
+	_, err = bot.SetWebhook(tgbotapi.NewWebhookWithCert("https://example.com/hook", tgbotapi.FileBytes{Name: "cert.pem", Bytes: []byte("cert")}))
+	assert.Nil(t, err)
+	call = s.CallsTo("setWebhook")[0]
+	assert.Equal(t, "https://example.com/hook", call.Params.Get("url"))
+	assert.Equal(t, []byte("cert"), call.Files["certificate"])
+
+	_, err = bot.RemoveWebhook()
+	assert.Nil(t, err)
+	assert.Empty(t, s.CallsTo("setWebhook")[1].Params.Get("url"))
+}
+
+func TestSendMediaGroup(t *testing.T) {
+	s, bot := newBot(t)
+
+	messages, err := bot.SendMediaGroup(tgbotapi.NewMediaGroup(42, []interface{}{
+		tgbotapi.NewInputMediaPhotoUpload(tgbotapi.FileBytes{Name: "a.png", Bytes: []byte("first")}),
+		tgbotapi.NewInputMediaPhoto("existing-file-id"),
+		tgbotapi.NewInputMediaPhotoUpload(tgbotapi.FileBytes{Name: "c.png", Bytes: []byte("third")}),
+	}))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, messages, 3)
+
+	calls := s.CallsTo("sendMediaGroup")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, []byte("first"), calls[0].Files["file-0"])
+		assert.Equal(t, []byte("third"), calls[0].Files["file-2"])
+		assert.JSONEq(t, `[
+			{"type":"photo","media":"attach://file-0"},
+			{"type":"photo","media":"existing-file-id"},
+			{"type":"photo","media":"attach://file-2"}
+		]`, calls[0].Params.Get("media"))
+	}
+
+	_, err = bot.Send(tgbotapi.NewMediaGroup(42, []interface{}{tgbotapi.NewInputMediaPhoto("existing-file-id")}))
+	assert.EqualError(t, err, tgbotapi.ErrMediaGroupSend)
+	assert.Len(t, s.CallsTo("sendMediaGroup"), 1)
+
+	_, err = bot.SendMediaGroup(tgbotapi.NewMediaGroup(42, []interface{}{
+		tgbotapi.NewInputMediaPhotoUpload(tgbotapi.FileBytes{Name: "a.png", Bytes: []byte("only")}),
+	}))
+	assert.EqualError(t, err, tgbotapi.ErrMediaGroupSize)
+	media := make([]interface{}, tgbotapi.MaxMediaGroupSize+1)
+	for i := range media {
+		media[i] = tgbotapi.NewInputMediaPhoto("existing-file-id")
+	}
+	_, err = bot.SendMediaGroup(tgbotapi.NewMediaGroup(42, media))
+	assert.EqualError(t, err, tgbotapi.ErrMediaGroupSize)
+	assert.Len(t, s.CallsTo("sendMediaGroup"), 1)
+}
+
+func TestPolls(t *testing.T) {
+	s, bot := newBot(t)
+
+	message, err := bot.Send(tgbotapi.NewQuiz(42, "2+2?", 1, "3", "4", "5"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.NotNil(t, message.Poll) {
+		assert.Len(t, message.Poll.Options, 3)
+		assert.True(t, message.Poll.IsQuiz())
+	}
+	assert.Equal(t, tgbotapi.ContentTypePoll, message.ContentType())
+
+	call := s.CallsTo("sendPoll")[0]
+	assert.Equal(t, "1", call.Params.Get("correct_option_id"))
+	assert.Equal(t, "true", call.Params.Get("is_anonymous"))
+
+	poll, err := bot.StopPoll(tgbotapi.NewStopPoll(42, message.MessageID))
+	assert.Nil(t, err)
+	assert.True(t, poll.IsClosed)
+
+	message, err = bot.Send(tgbotapi.NewDiceWithEmoji(42, tgbotapi.DartsEmoji))
+	if assert.Nil(t, err) && assert.NotNil(t, message.Dice) {
+		assert.Equal(t, tgbotapi.DartsEmoji, message.Dice.Emoji)
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	s, bot := newBot(t)
+
+	var items []tgbotapi.InlineKeyboardButton
+	for i := 0; i < 12; i++ {
+		items = append(items, tgbotapi.NewInlineKeyboardButtonData("item", "x"))
+	}
+	p := tgbotapi.NewPaginator(tgbotapi.NewCallbackCodec("page"), func() []tgbotapi.InlineKeyboardButton {
+		return items
+	})
+
+	r := tgbotapi.NewRouter(bot)
+	p.Register(r)
+	r.HandleUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "q1",
+		Data:    "page:2",
+		Message: &tgbotapi.Message{MessageID: 5, Chat: &tgbotapi.Chat{ID: 42}},
+	}})
+
+	calls := s.CallsTo("editMessageReplyMarkup")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "5", calls[0].Params.Get("message_id"))
+		assert.Contains(t, calls[0].Params.Get("reply_markup"), `"3/3"`)
+	}
+	assert.Len(t, s.CallsTo("answerCallbackQuery"), 1)
+}
+
+func TestSendLong(t *testing.T) {
+	s, bot := newBot(t)
+
+	text := strings.Repeat("word ", 2000)
+	config := tgbotapi.NewMessage(42, text)
+	config.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("ok", "ok"),
+	))
+
+	messages, err := bot.SendLong(config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, messages, 3)
+
+	calls := s.CallsTo("sendMessage")
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, "", calls[0].Params.Get("reply_markup"))
+		assert.Equal(t, strconv.Itoa(messages[0].MessageID), calls[1].Params.Get("reply_to_message_id"))
+		assert.NotEqual(t, "", calls[2].Params.Get("reply_markup"))
+		for _, call := range calls {
+			assert.True(t, tgbotapi.UTF16Len(call.Params.Get("text")) <= tgbotapi.MaxMessageTextLength)
+		}
+	}
+
+	photo := tgbotapi.NewPhotoShare(42, "photo")
+	photo.Caption = strings.Repeat("word ", 300)
+	photo.ReplyMarkup = config.ReplyMarkup
+	messages, err = bot.SendLongCaption(photo)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, messages, 2)
+	if calls := s.CallsTo("sendPhoto"); assert.Len(t, calls, 1) {
+		assert.Equal(t, "", calls[0].Params.Get("reply_markup"))
+	}
+	if calls := s.CallsTo("sendMessage"); assert.Len(t, calls, 4) {
+		assert.NotEqual(t, "", calls[3].Params.Get("reply_markup"))
+	}
+}
+
+func TestChatJoinRequest(t *testing.T) {
+	s, bot := newBot(t)
+
+	r := tgbotapi.NewRouter(bot)
+	r.ChatJoinRequest(func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		request := update.ChatJoinRequest
+		bot.ApproveChatJoinRequest(tgbotapi.ChatMemberConfig{ChatID: request.Chat.ID, UserID: request.From.ID})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := tgbotapi.NewUpdate(0)
+	config.AllowedUpdates = []string{tgbotapi.UpdateTypeMessage, tgbotapi.UpdateTypeChatJoinRequest}
+	poller := bot.StartPolling(ctx, config)
+
+	s.PushUpdate(tgbotapi.Update{ChatJoinRequest: &tgbotapi.ChatJoinRequest{
+		Chat: tgbotapi.Chat{ID: -100, Type: "supergroup"},
+		From: tgbotapi.User{ID: 7},
+	}})
+	update := <-poller.Updates()
+	assert.Equal(t, 7, update.SentFrom().ID)
+	assert.Equal(t, int64(-100), update.FromChat().ID)
+	r.HandleUpdate(update)
+
+	calls := s.CallsTo("approveChatJoinRequest")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "-100", calls[0].Params.Get("chat_id"))
+		assert.Equal(t, "7", calls[0].Params.Get("user_id"))
+	}
+	assert.Equal(t, `["message","chat_join_request"]`, s.CallsTo("getUpdates")[0].Params.Get("allowed_updates"))
+}
+
+func TestForumTopics(t *testing.T) {
+	s, bot := newBot(t)
+
+	topic, err := bot.CreateForumTopic(tgbotapi.NewCreateForumTopic(-100, "News"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "News", topic.Name)
+
+	config := tgbotapi.NewMessage(-100, "hello")
+	config.MessageThreadID = topic.MessageThreadID
+	message, err := bot.Send(config)
+	assert.Nil(t, err)
+
+	var got []string
+	r := tgbotapi.NewRouter(bot)
+	r.Topic(topic.MessageThreadID, func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		got = append(got, "news")
+	})
+	r.Topic(0, func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		got = append(got, "general")
+	})
+	r.HandleUpdate(tgbotapi.Update{Message: &message})
+	general := message
+	general.IsTopicMessage = false
+	general.MessageThreadID = 0
+	r.HandleUpdate(tgbotapi.Update{Message: &general})
+	assert.Equal(t, []string{"news", "general"}, got)
+
+	_, err = bot.CloseForumTopic(tgbotapi.NewCloseForumTopic(-100, topic.MessageThreadID))
+	assert.Nil(t, err)
+	calls := s.CallsTo("closeForumTopic")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, strconv.Itoa(topic.MessageThreadID), calls[0].Params.Get("message_thread_id"))
+	}
+}
+
+func TestPublishCommands(t *testing.T) {
+	s, bot := newBot(t)
+
+	r := tgbotapi.NewRouter(bot)
+	r.CommandWithDescription("start", "Start the bot", func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {})
+	r.Command("hidden", func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {})
+	r.CommandWithDescription("help", "Show help", func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {})
+	r.AutoPublishCommands(
+		tgbotapi.NewSetMyCommands(),
+		tgbotapi.NewSetMyCommandsWithScope(tgbotapi.NewBotCommandScopeChat(42), "de"),
+	)
+
+	updates := make(chan tgbotapi.Update)
+	close(updates)
+	r.Run(updates)
+
+	calls := s.CallsTo("setMyCommands")
+	if assert.Len(t, calls, 2) {
+		assert.JSONEq(t, `[{"command":"start","description":"Start the bot"},{"command":"help","description":"Show help"}]`,
+			calls[0].Params.Get("commands"))
+		assert.Equal(t, "", calls[0].Params.Get("scope"))
+		assert.JSONEq(t, `{"type":"chat","chat_id":42}`, calls[1].Params.Get("scope"))
+		assert.Equal(t, "de", calls[1].Params.Get("language_code"))
+	}
+
+	commands, err := bot.GetMyCommands(tgbotapi.NewGetMyCommandsWithScope(tgbotapi.NewBotCommandScopeDefault(), ""))
+	assert.Nil(t, err)
+	assert.Equal(t, r.Commands(), commands)
+
+	_, err = bot.DeleteMyCommands(tgbotapi.NewDeleteMyCommands())
+	assert.Nil(t, err)
+	commands, err = bot.GetMyCommands(tgbotapi.NewGetMyCommands())
+	assert.Nil(t, err)
+	assert.Empty(t, commands)
+}
+
+func TestDownloadFile(t *testing.T) {
+	s, bot := newBot(t)
+	content := []byte(strings.Repeat("0123456789", 100))
+	s.AddFile("doc", content)
+
+	var buf bytes.Buffer
+	file, err := bot.DownloadFile(context.Background(), "doc", &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content), file.FileSize)
+	assert.Equal(t, content, buf.Bytes())
+
+	_, err = bot.DownloadFile(context.Background(), "missing", &buf)
+	assert.NotNil(t, err)
+}
+
+func TestDownloadToPath(t *testing.T) {
+	s, bot := newBot(t)
+	content := []byte(strings.Repeat("0123456789", 100))
+	s.AddFile("doc", content)
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "sub", "dir", "doc.txt")
+	_, err := bot.DownloadToPath(context.Background(), "doc", filePath)
+	if !assert.Nil(t, err) {
+		return
+	}
+	got, err := os.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, got)
+
+	filePath = filepath.Join(dir, "sub", "dir", "resumed.txt")
+	assert.Nil(t, os.WriteFile(filePath+".part", content[:300], 0644))
+	_, err = bot.DownloadToPath(context.Background(), "doc", filePath)
+	if !assert.Nil(t, err) {
+		return
+	}
+	got, err = os.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, got)
+	_, err = os.Stat(filePath + ".part")
+	assert.True(t, os.IsNotExist(err))
+
+	local := filepath.Join(dir, "local.bin")
+	assert.Nil(t, os.WriteFile(local, []byte("local"), 0644))
+	s.Handle("getFile", func(call Call) (interface{}, *tgbotapi.Error) {
+		return tgbotapi.File{FileID: "local", FileSize: 5, FilePath: local}, nil
+	})
+	var buf bytes.Buffer
+	_, err = bot.DownloadFile(context.Background(), "local", &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "local", buf.String())
+}
+
+func TestBroadcast(t *testing.T) {
+	s, bot := newBot(t)
+	s.Handle("sendMessage", func(call Call) (interface{}, *tgbotapi.Error) {
+		switch call.Params.Get("chat_id") {
+		case "2":
+			return nil, &tgbotapi.Error{Code: http.StatusForbidden, Message: "Forbidden: bot was blocked by the user"}
+		case "3":
+			return nil, &tgbotapi.Error{Code: http.StatusForbidden, Message: "Forbidden: user is deactivated"}
+		case "4":
+			return nil, &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: chat not found"}
+		}
+		return s.message(call), nil
+	})
+
+	checkpoint := filepath.Join(t.TempDir(), "broadcast.json")
+	b := tgbotapi.NewBroadcast(bot, tgbotapi.NewMessage(0, "news"), []int64{1, 2, 3, 4, 5, 1})
+	b.CheckpointPath = checkpoint
+	var updates int
+	b.OnProgress = func(progress tgbotapi.BroadcastProgress) { updates++ }
+
+	progress, err := b.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, tgbotapi.BroadcastProgress{Total: 5, Done: 5, Sent: 2, Blocked: 1, Deactivated: 1, Failed: 1}, progress)
+	assert.Equal(t, 5, updates)
+	assert.Len(t, s.CallsTo("sendMessage"), 5)
+
+	results := b.Results()
+	if assert.Len(t, results, 5) {
+		assert.Equal(t, tgbotapi.BroadcastSent, results[0].Status)
+		assert.NotZero(t, results[0].MessageID)
+		assert.Equal(t, tgbotapi.BroadcastBlocked, results[1].Status)
+		assert.Equal(t, tgbotapi.BroadcastDeactivated, results[2].Status)
+		assert.Equal(t, tgbotapi.BroadcastError, results[3].Status)
+		assert.NotEmpty(t, results[3].Error)
+	}
+
+	b = tgbotapi.NewBroadcast(bot, tgbotapi.NewMessage(0, "news"), []int64{1, 2, 3, 4, 5, 6})
+	b.CheckpointPath = checkpoint
+	progress, err = b.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, tgbotapi.BroadcastProgress{Total: 6, Done: 6, Sent: 3, Blocked: 1, Deactivated: 1, Failed: 1}, progress)
+
+	calls := s.CallsTo("sendMessage")
+	if assert.Len(t, calls, 7) {
+		retried := []string{calls[5].Params.Get("chat_id"), calls[6].Params.Get("chat_id")}
+		assert.ElementsMatch(t, []string{"4", "6"}, retried)
+	}
+}
+
+func TestPollingWithStore(t *testing.T) {
+	s, bot := newBot(t)
+	store := tgbotapi.NewFileOffsetStore(filepath.Join(t.TempDir(), "offset.json"))
+	first := s.PushMessage(7, "one")
+	second := s.PushMessage(7, "two")
+	third := s.PushMessage(7, "three")
+
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+
+	ctx, cancel := context.WithCancel(context.Background())
+	poller, err := bot.StartPollingWithStore(ctx, config, store)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, want := range []tgbotapi.Update{first, second, third} {
+		update := <-poller.Updates()
+		assert.Equal(t, want.UpdateID, update.UpdateID)
+	}
+	assert.Nil(t, poller.Ack(second.UpdateID))
+	assert.Equal(t, 0, poller.Offset())
+
+	cancel()
+	<-poller.Done()
+
+	state, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, tgbotapi.OffsetState{Processed: []int{second.UpdateID}}, state)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	poller, err = bot.StartPollingWithStore(ctx, config, store)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var handled []string
+	r := tgbotapi.NewRouter(bot)
+	r.Use(poller.Middleware())
+	r.NotFound(func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		handled = append(handled, update.Message.Text)
+	})
+	r.HandleUpdate(<-poller.Updates())
+	r.HandleUpdate(<-poller.Updates())
+
+	assert.Equal(t, []string{"one", "three"}, handled)
+	assert.Equal(t, third.UpdateID+1, poller.Offset())
+	state, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, tgbotapi.OffsetState{Offset: third.UpdateID + 1}, state)
+}
+
+func TestPollingWithStoreUnackedUpdate(t *testing.T) {
+	s, bot := newBot(t)
+	stuck := s.PushMessage(7, "stuck")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	poller, err := bot.StartPollingWithStore(ctx, tgbotapi.NewUpdate(0), tgbotapi.NewMemoryOffsetStore())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, stuck.UpdateID, (<-poller.Updates()).UpdateID)
+
+	// Let the poller fetch the unacknowledged update alone again.
+	assert.Eventually(t, func() bool {
+		return len(s.CallsTo("getUpdates")) >= 2
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	next := s.PushMessage(7, "next")
+	select {
+	case update := <-poller.Updates():
+		assert.Equal(t, next.UpdateID, update.UpdateID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("update after an unacknowledged one never delivered")
+	}
+	assert.Equal(t, 0, poller.Offset())
+
+	r := tgbotapi.NewRouter(bot)
+	r.Use(tgbotapi.RecoverMiddleware(), poller.Middleware())
+	r.NotFound(func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		panic("boom")
+	})
+	r.HandleUpdate(stuck)
+	r.HandleUpdate(next)
+	assert.Equal(t, next.UpdateID+1, poller.Offset())
+}
+
+func TestPayments(t *testing.T) {
+	s, bot := newBot(t)
+
+	link, err := bot.CreateInvoiceLink(tgbotapi.NewStarsInvoiceLink("Title", "Description", "order-1", 50))
+	assert.Nil(t, err)
+	assert.Equal(t, "https://t.me/$order-1", link)
+
+	_, err = bot.RefundStarPayment(tgbotapi.NewRefundStarPayment(7, "charge"))
+	assert.Nil(t, err)
+	calls := s.CallsTo("refundStarPayment")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "7", calls[0].Params.Get("user_id"))
+		assert.Equal(t, "charge", calls[0].Params.Get("telegram_payment_charge_id"))
+	}
+}
+
+func TestDo(t *testing.T) {
+	s, bot := newBot(t)
+
+	message, err := tgbotapi.Do(bot, tgbotapi.NewMessage(42, "hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", message.Text)
+
+	edit := tgbotapi.EditMessageTextConfig{BaseEdit: tgbotapi.BaseEdit{InlineMessageID: "inline"}, Text: "edited"}
+	message, err = tgbotapi.Do(bot, edit)
+	assert.Nil(t, err)
+	assert.Zero(t, message.MessageID)
+
+	commands := []tgbotapi.BotCommand{tgbotapi.NewBotCommand("start", "Start the bot")}
+	ok, err := tgbotapi.Do(bot, tgbotapi.NewSetMyCommandsWithScope(tgbotapi.NewBotCommandScopeChat(42), "", commands...))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	calls := s.CallsTo("setMyCommands")
+	if assert.Len(t, calls, 1) {
+		assert.JSONEq(t, `{"type":"chat","chat_id":42}`, calls[0].Params.Get("scope"))
+	}
+
+	s.Handle("getMyCommands", func(call Call) (interface{}, *tgbotapi.Error) {
+		return "not a list", nil
+	})
+	_, err = tgbotapi.Do(bot, tgbotapi.NewGetMyCommands())
+	assert.NotNil(t, err)
+
+	s.Fail("sendMessage", &tgbotapi.Error{
+		Code:               http.StatusBadRequest,
+		Message:            "Bad Request: group chat was upgraded to a supergroup chat",
+		ResponseParameters: tgbotapi.ResponseParameters{MigrateToChatID: -1001},
+	})
+	_, err = bot.MakeJSONRequest("sendMessage", map[string]interface{}{"chat_id": -1, "text": "hello"})
+	assert.Nil(t, err)
+	if calls := s.CallsTo("sendMessage"); assert.Len(t, calls, 3) {
+		assert.Equal(t, "-1001", calls[2].Params.Get("chat_id"))
+		assert.Equal(t, "hello", calls[2].Params.Get("text"))
+	}
+
+	message, err = bot.Send(tgbotapi.InlineConfig{InlineQueryID: "q", Results: []interface{}{}})
+	assert.Nil(t, err)
+	assert.Zero(t, message.MessageID)
+	assert.Len(t, s.CallsTo("answerInlineQuery"), 1)
+}
+
+func TestInlineHandler(t *testing.T) {
+	s, bot := newBot(t)
+
+	h := tgbotapi.NewInlineHandler(func(query tgbotapi.InlineQuery) ([]interface{}, error) {
+		return []interface{}{
+			tgbotapi.NewInlineQueryResultCachedSticker("1", "sticker"),
+			tgbotapi.NewInlineQueryResultVenue("2", "Office", "Main St 1", 1.5, 2.5),
+		}, nil
+	})
+	button := tgbotapi.NewInlineQueryResultsButton("Sign in", "login")
+	h.Button = &button
+	h.PageSize = 1
+
+	h.Handle(bot, tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{ID: "query", Query: "office"}})
+
+	calls := s.CallsTo("answerInlineQuery")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "query", calls[0].Params.Get("inline_query_id"))
+		assert.Equal(t, "1", calls[0].Params.Get("next_offset"))
+		assert.JSONEq(t, `[{"type":"sticker","id":"1","sticker_file_id":"sticker"}]`, calls[0].Params.Get("results"))
+		assert.JSONEq(t, `{"text":"Sign in","start_parameter":"login"}`, calls[0].Params.Get("button"))
+	}
+}
+
+func TestLiveMessage(t *testing.T) {
+	s, bot := newBot(t)
+
+	live, err := tgbotapi.StartLiveMessage(bot, tgbotapi.NewMessage(42, "processing"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	live.Interval = 50 * time.Millisecond
+	for i := 1; i <= 10; i++ {
+		live.SetText("processing " + strconv.Itoa(i*10) + "%")
+	}
+	live.SetText("done")
+	assert.Nil(t, live.Close(context.Background()))
+
+	calls := s.CallsTo("editMessageText")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "done", calls[0].Params.Get("text"))
+	}
+
+	live.SetText("done")
+	assert.Nil(t, live.Close(context.Background()))
+	assert.Len(t, s.CallsTo("editMessageText"), 1)
+
+	inline := tgbotapi.NewInlineLiveMessage(bot, "inline")
+	inline.Interval = 0
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Stop", "stop")))
+	inline.SetReplyMarkup(&markup)
+	assert.Nil(t, inline.Close(context.Background()))
+	assert.Nil(t, inline.Err())
+
+	calls = s.CallsTo("editMessageReplyMarkup")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "inline", calls[0].Params.Get("inline_message_id"))
+	}
+
+	s.Fail("editMessageText", &tgbotapi.Error{Code: http.StatusBadGateway, Message: "Bad Gateway"})
+	retried := tgbotapi.NewLiveMessage(bot, 42, 8)
+	retried.Interval = 20 * time.Millisecond
+	retried.SetText("retried")
+	assert.Eventually(t, func() bool {
+		return len(s.CallsTo("editMessageText")) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Nil(t, retried.Err())
+	assert.Nil(t, retried.Close(context.Background()))
+
+	retried.SetText("after close")
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, s.CallsTo("editMessageText"), 3)
+
+	s.Fail("editMessageText", &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: message to edit not found"})
+	deleted := tgbotapi.NewLiveMessage(bot, 42, 9)
+	deleted.Interval = 10 * time.Millisecond
+	deleted.SetText("gone")
+	assert.Eventually(t, func() bool {
+		return deleted.Err() != nil
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, s.CallsTo("editMessageText"), 4)
+}
+
+func TestEditMessageMedia(t *testing.T) {
+	s, bot := newBot(t)
+
+	media := tgbotapi.NewInputMediaPhotoUpload(tgbotapi.FileBytes{Name: "a.png", Bytes: []byte("png")})
+	_, err := bot.EditMessageMedia(tgbotapi.NewEditMessageMedia(42, 7, media))
+	assert.Nil(t, err)
+
+	calls := s.CallsTo("editMessageMedia")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, []byte("png"), calls[0].Files["file-0"])
+		assert.JSONEq(t, `{"type":"photo","media":"attach://file-0"}`, calls[0].Params.Get("media"))
+	}
+
+	_, err = bot.EditMessageLiveLocation(tgbotapi.NewEditMessageLiveLocation(42, 8, 1.5, 2.5))
+	assert.Nil(t, err)
+	calls = s.CallsTo("editMessageLiveLocation")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "8", calls[0].Params.Get("message_id"))
+		assert.Equal(t, "1.500000", calls[0].Params.Get("latitude"))
+	}
+}
+
+func TestModeration(t *testing.T) {
+	s, bot := newBot(t)
+
+	member := tgbotapi.ChatMemberConfig{ChatID: -100, UserID: 7}
+	banned, err := bot.BanChatMemberFor(member, time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, banned)
+	muted, err := bot.MuteChatMember(member, 0)
+	assert.Nil(t, err)
+	assert.True(t, muted)
+
+	if calls := s.CallsTo("banChatMember"); assert.Len(t, calls, 1) {
+		until, _ := strconv.ParseInt(calls[0].Params.Get("until_date"), 10, 64)
+		assert.InDelta(t, time.Now().Add(time.Hour).Unix(), until, 5)
+	}
+	if calls := s.CallsTo("restrictChatMember"); assert.Len(t, calls, 1) {
+		var permissions tgbotapi.ChatPermissions
+		assert.Nil(t, json.Unmarshal([]byte(calls[0].Params.Get("permissions")), &permissions))
+		assert.Equal(t, tgbotapi.ChatPermissions{}, permissions)
+		assert.Empty(t, calls[0].Params.Get("can_send_messages"))
+		assert.Empty(t, calls[0].Params.Get("until_date"))
+	}
+
+	recent := tgbotapi.NewRecentMessages()
+	recent.Limit = 0
+	for i := 1; i <= 150; i++ {
+		recent.Record(&tgbotapi.Message{MessageID: i, Chat: &tgbotapi.Chat{ID: -100}, From: &tgbotapi.User{ID: 7}})
+	}
+	assert.Nil(t, recent.Delete(bot, -100, 7))
+	assert.Len(t, s.CallsTo("deleteMessages"), 2)
+
+	set, err := bot.SetChatPermissions(tgbotapi.NewSetChatPermissions(-100, tgbotapi.ChatPermissions{CanSendMessages: true}))
+	assert.Nil(t, err)
+	assert.True(t, set)
+	assert.Empty(t, recent.IDs(-100, 7))
+
+	admins := tgbotapi.NewAdminCache(bot)
+	var handled []int
+	handler := tgbotapi.Chain(func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		handled = append(handled, update.Message.From.ID)
+	}, admins.Middleware())
+	for _, userID := range []int{bot.Self.ID, 7} {
+		handler(bot, tgbotapi.Update{Message: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: -100, Type: "supergroup"},
+			From: &tgbotapi.User{ID: userID},
+		}})
+	}
+	assert.Equal(t, []int{bot.Self.ID}, handled)
+	assert.Len(t, s.CallsTo("getChatAdministrators"), 1)
+
+	admins.Invalidate(-100)
+	admin, err := admins.IsAdmin(-100, 7)
+	assert.Nil(t, err)
+	assert.False(t, admin)
+	assert.Len(t, s.CallsTo("getChatAdministrators"), 2)
+}
+
+func TestCaptcha(t *testing.T) {
+	s, bot := newBot(t)
+
+	codec := tgbotapi.NewCallbackCodec("captcha")
+	captcha := tgbotapi.NewCaptcha(codec)
+	r := tgbotapi.NewRouter(bot)
+	captcha.Register(r)
+
+	chat := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	r.HandleUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		Chat:           chat,
+		From:           &tgbotapi.User{ID: 7},
+		NewChatMembers: &[]tgbotapi.User{{ID: 7, FirstName: "Ann"}, {ID: 9, IsBot: true}},
+	}})
+	r.HandleUpdate(tgbotapi.Update{ChatMember: &tgbotapi.ChatMemberUpdated{
+		Chat:          *chat,
+		OldChatMember: tgbotapi.ChatMember{User: &tgbotapi.User{ID: 7}, Status: "left"},
+		NewChatMember: tgbotapi.ChatMember{User: &tgbotapi.User{ID: 7}, Status: "member"},
+	}})
+
+	assert.Len(t, s.CallsTo("restrictChatMember"), 1)
+	sends := s.CallsTo("sendMessage")
+	if !assert.Len(t, sends, 1) {
+		return
+	}
+	assert.Contains(t, sends[0].Params.Get("text"), "Ann")
+
+	data, err := codec.Encode(7)
+	assert.Nil(t, err)
+	query := &tgbotapi.CallbackQuery{ID: "q1", From: &tgbotapi.User{ID: 8}, Message: &tgbotapi.Message{Chat: chat}, Data: data}
+	r.HandleUpdate(tgbotapi.Update{CallbackQuery: query})
+	if answers := s.CallsTo("answerCallbackQuery"); assert.Len(t, answers, 1) {
+		assert.NotEmpty(t, answers[0].Params.Get("text"))
+	}
+	assert.Len(t, s.CallsTo("restrictChatMember"), 1)
+
+	query.From = &tgbotapi.User{ID: 7}
+	r.HandleUpdate(tgbotapi.Update{CallbackQuery: query})
+	restricts := s.CallsTo("restrictChatMember")
+	if assert.Len(t, restricts, 2) {
+		assert.Contains(t, restricts[1].Params.Get("permissions"), `"can_send_messages":true`)
+	}
+	assert.Len(t, s.CallsTo("deleteMessage"), 1)
+
+	// A prompt whose challenge is no longer pending still unmutes.
+	query.Message = &tgbotapi.Message{MessageID: 5, Chat: chat}
+	r.HandleUpdate(tgbotapi.Update{CallbackQuery: query})
+	restricts = s.CallsTo("restrictChatMember")
+	if assert.Len(t, restricts, 3) {
+		assert.Equal(t, "7", restricts[2].Params.Get("user_id"))
+	}
+	if deletes := s.CallsTo("deleteMessage"); assert.Len(t, deletes, 2) {
+		assert.Equal(t, "5", deletes[1].Params.Get("message_id"))
+	}
+
+	captcha.Timeout = 10 * time.Millisecond
+	assert.Nil(t, captcha.Challenge(bot, -100, tgbotapi.User{ID: 8}))
+	assert.Eventually(t, func() bool {
+		return len(s.CallsTo("deleteMessage")) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, s.CallsTo("banChatMember"), 1)
+}
+
+func TestBotManager(t *testing.T) {
+	polled, hooked := NewServer(), NewServer()
+	t.Cleanup(polled.Close)
+	t.Cleanup(hooked.Close)
+	hooked.Token = "654321:OTHER-TOKEN"
+	hooked.Fail("getMe", &tgbotapi.Error{Code: http.StatusUnauthorized, Message: "Unauthorized"})
+	hooked.Fail("setWebhook", &tgbotapi.Error{Code: http.StatusInternalServerError, Message: "Internal Server Error"})
+
+	m := tgbotapi.NewBotManager()
+	m.UpdateConfig.Timeout = 1
+	m.RetryDelay = 10 * time.Millisecond
+
+	updates := make(chan tgbotapi.Update, 2)
+	handler := func(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+		updates <- update
+	}
+
+	m.APIEndpoint = polled.APIEndpoint()
+	_, err := m.Add(polled.Token, handler)
+	assert.Nil(t, err)
+	_, err = m.Add(polled.Token, handler)
+	assert.NotNil(t, err)
+
+	m.APIEndpoint = hooked.APIEndpoint()
+	config := tgbotapi.NewWebhook("https://example.com/hooks/b")
+	config.SecretToken = "secret"
+	_, err = m.AddWebhook(hooked.Token, config, handler)
+	assert.Nil(t, err)
+
+	post := func(path, secret string, updateID int) int {
+		body, _ := json.Marshal(tgbotapi.Update{UpdateID: updateID})
+		r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		r.Header.Set(tgbotapi.WebhookSecretHeader, secret)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, post("/hooks/b", "secret", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+	// A second start is ignored, so that the bots stop with ctx.
+	m.Start(context.Background())
+
+	polled.PushMessage(42, "hello")
+	select {
+	case update := <-updates:
+		assert.Equal(t, "hello", update.Message.Text)
+	case <-time.After(time.Second):
+		t.Fatal("no polled update")
+	}
+
+	assert.Eventually(t, func() bool {
+		return m.Bot(hooked.Token).Health().Loaded
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, hooked.CallsTo("setWebhook"), 2)
+	assert.Equal(t, http.StatusOK, post("/hooks/b", "secret", 10))
+	assert.Equal(t, http.StatusOK, post("/other", "secret", 11))
+	assert.Equal(t, http.StatusUnauthorized, post("/hooks/b", "wrong", 12))
+	assert.Equal(t, http.StatusNotFound, post("/other", "wrong", 13))
+	for _, id := range []int{10, 11} {
+		select {
+		case update := <-updates:
+			assert.Equal(t, id, update.UpdateID)
+		case <-time.After(time.Second):
+			t.Fatal("no webhook update")
+		}
+	}
+
+	health := m.Health()
+	if assert.Len(t, health, 2) {
+		assert.Equal(t, "123456", health[0].ID)
+		assert.Equal(t, "test_bot", health[0].UserName)
+		assert.Equal(t, 1, health[0].Updates)
+		assert.Zero(t, health[0].Errors)
+
+		assert.True(t, health[1].Webhook)
+		assert.Equal(t, "test_bot", health[1].UserName)
+		assert.Equal(t, 2, health[1].Updates)
+		assert.Equal(t, 2, health[1].Errors)
+		assert.Contains(t, health[1].LastError, "Internal Server Error")
+	}
+
+	cancel()
+	m.Wait()
+	for _, h := range m.Health() {
+		assert.False(t, h.Running)
+	}
+}
+
+func TestBotManagerLoading(t *testing.T) {
+	s := NewServer()
+	t.Cleanup(s.Close)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	s.Handle("getMe", func(call Call) (interface{}, *tgbotapi.Error) {
+		<-release
+		return s.Self, nil
+	})
+
+	m := tgbotapi.NewBotManager()
+	m.APIEndpoint = s.APIEndpoint()
+	_, err := m.AddWebhook(s.Token, tgbotapi.NewWebhook("https://example.com/"), nil)
+	assert.NotNil(t, err)
+	_, err = m.AddWebhook(s.Token, tgbotapi.NewWebhook("https://example.com/hooks/a"), nil)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+	assert.Eventually(t, func() bool {
+		return len(s.CallsTo("getMe")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	body, _ := json.Marshal(tgbotapi.Update{UpdateID: 1})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hooks/a", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.True(t, m.Bot(s.Token).Health().Running)
+
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		m.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("manager blocked by a pending request")
+	}
+	assert.False(t, m.Bot(s.Token).Health().Running)
+}
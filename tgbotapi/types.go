@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 )
@@ -37,6 +38,67 @@ type Update struct {
 	CallbackQuery      *CallbackQuery      `json:"callback_query"`
 	ShippingQuery      *ShippingQuery      `json:"shipping_query"`
 	PreCheckoutQuery   *PreCheckoutQuery   `json:"pre_checkout_query"`
+	Poll               *Poll               `json:"poll"`
+	PollAnswer         *PollAnswer         `json:"poll_answer"`
+	MyChatMember       *ChatMemberUpdated  `json:"my_chat_member"`
+	ChatMember         *ChatMemberUpdated  `json:"chat_member"`
+	ChatJoinRequest    *ChatJoinRequest    `json:"chat_join_request"`
+}
+
+// SentFrom returns the user who sent an update. Can be nil, if Telegram
+// did not provide information about the user in the update object.
+func (u *Update) SentFrom() *User {
+	switch {
+	case u.Message != nil:
+		return u.Message.From
+	case u.EditedMessage != nil:
+		return u.EditedMessage.From
+	case u.InlineQuery != nil:
+		return u.InlineQuery.From
+	case u.ChosenInlineResult != nil:
+		return u.ChosenInlineResult.From
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.From
+	case u.ShippingQuery != nil:
+		return u.ShippingQuery.From
+	case u.PreCheckoutQuery != nil:
+		return u.PreCheckoutQuery.From
+	case u.PollAnswer != nil:
+		return u.PollAnswer.User
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.From
+	case u.ChatMember != nil:
+		return &u.ChatMember.From
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.From
+	default:
+		return nil
+	}
+}
+
+// FromChat returns the chat where an update occurred. Can be nil for
+// updates that are not bound to a chat, such as inline queries.
+func (u *Update) FromChat() *Chat {
+	switch {
+	case u.Message != nil:
+		return u.Message.Chat
+	case u.EditedMessage != nil:
+		return u.EditedMessage.Chat
+	case u.ChannelPost != nil:
+		return u.ChannelPost.Chat
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost.Chat
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		return u.CallbackQuery.Message.Chat
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.Chat
+	case u.ChatMember != nil:
+		return &u.ChatMember.Chat
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.Chat
+	default:
+		return nil
+	}
 }
 
 // UpdatesChannel is the channel for getting updates.
@@ -100,6 +162,7 @@ type Chat struct {
 	Photo               *ChatPhoto `json:"photo"`
 	Description         string     `json:"description,omitempty"` // optional
 	InviteLink          string     `json:"invite_link,omitempty"` // optional
+	IsForum             bool       `json:"is_forum,omitempty"`    // optional
 }
 
 // IsPrivate returns if the Chat is a private conversation.
@@ -167,6 +230,16 @@ type Message struct {
 	PinnedMessage         *Message           `json:"pinned_message"`          // optional
 	Invoice               *Invoice           `json:"invoice"`                 // optional
 	SuccessfulPayment     *SuccessfulPayment `json:"successful_payment"`      // optional
+	RefundedPayment       *RefundedPayment   `json:"refunded_payment"`        // optional
+	MediaGroupID          string             `json:"media_group_id"`          // optional
+	MessageThreadID       int                `json:"message_thread_id"`       // optional
+	IsTopicMessage        bool               `json:"is_topic_message"`        // optional
+	ForumTopicCreated     *ForumTopicCreated `json:"forum_topic_created"`     // optional
+	ForumTopicEdited      *ForumTopicEdited  `json:"forum_topic_edited"`      // optional
+	ForumTopicClosed      *struct{}          `json:"forum_topic_closed"`      // optional
+	ForumTopicReopened    *struct{}          `json:"forum_topic_reopened"`    // optional
+	Poll                  *Poll              `json:"poll"`                    // optional
+	Dice                  *Dice              `json:"dice"`                    // optional
 }
 
 // Time converts the message timestamp into a Time.
@@ -236,13 +309,85 @@ func (m *Message) CommandArguments() string {
 	return m.Text[entity.Length+1:]
 }
 
+// Constant values returned by Message.ContentType.
+const (
+	ContentTypeText           = "text"
+	ContentTypeAudio          = "audio"
+	ContentTypeDocument       = "document"
+	ContentTypeGame           = "game"
+	ContentTypePhoto          = "photo"
+	ContentTypeSticker        = "sticker"
+	ContentTypeVideo          = "video"
+	ContentTypeVideoNote      = "video_note"
+	ContentTypeVoice          = "voice"
+	ContentTypeContact        = "contact"
+	ContentTypeLocation       = "location"
+	ContentTypeVenue          = "venue"
+	ContentTypeNewChatMembers = "new_chat_members"
+	ContentTypeLeftChatMember = "left_chat_member"
+	ContentTypeInvoice        = "invoice"
+	ContentTypePayment        = "successful_payment"
+	ContentTypeRefund         = "refunded_payment"
+	ContentTypePoll           = "poll"
+	ContentTypeDice           = "dice"
+	ContentTypeUnknown        = "unknown"
+)
+
+// ContentType returns the kind of content carried by the message, as one
+// of the ContentType constants.
+func (m *Message) ContentType() string {
+	switch {
+	case m.Audio != nil:
+		return ContentTypeAudio
+	case m.Document != nil:
+		return ContentTypeDocument
+	case m.Game != nil:
+		return ContentTypeGame
+	case m.Photo != nil:
+		return ContentTypePhoto
+	case m.Sticker != nil:
+		return ContentTypeSticker
+	case m.Video != nil:
+		return ContentTypeVideo
+	case m.VideoNote != nil:
+		return ContentTypeVideoNote
+	case m.Voice != nil:
+		return ContentTypeVoice
+	case m.Contact != nil:
+		return ContentTypeContact
+	case m.Venue != nil:
+		return ContentTypeVenue
+	case m.Location != nil:
+		return ContentTypeLocation
+	case m.NewChatMembers != nil:
+		return ContentTypeNewChatMembers
+	case m.LeftChatMember != nil:
+		return ContentTypeLeftChatMember
+	case m.Invoice != nil:
+		return ContentTypeInvoice
+	case m.SuccessfulPayment != nil:
+		return ContentTypePayment
+	case m.RefundedPayment != nil:
+		return ContentTypeRefund
+	case m.Poll != nil:
+		return ContentTypePoll
+	case m.Dice != nil:
+		return ContentTypeDice
+	case m.Text != "":
+		return ContentTypeText
+	default:
+		return ContentTypeUnknown
+	}
+}
+
 // MessageEntity contains information about data in a Message.
 type MessageEntity struct {
-	Type   string `json:"type"`
-	Offset int    `json:"offset"`
-	Length int    `json:"length"`
-	URL    string `json:"url"`  // optional
-	User   *User  `json:"user"` // optional
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	URL      string `json:"url,omitempty"`      // optional
+	User     *User  `json:"user,omitempty"`     // optional
+	Language string `json:"language,omitempty"` // optional
 }
 
 // ParseURL attempts to parse a URL contained within a MessageEntity.
@@ -343,6 +488,86 @@ type Venue struct {
 	FoursquareID string   `json:"foursquare_id"` // optional
 }
 
+// PollOption contains information about one answer option in a poll.
+type PollOption struct {
+	Text       string `json:"text"`
+	VoterCount int    `json:"voter_count"`
+}
+
+// PollAnswer represents an answer of a user in a non-anonymous poll.
+type PollAnswer struct {
+	PollID    string `json:"poll_id"`
+	User      *User  `json:"user"`
+	OptionIDs []int  `json:"option_ids"`
+}
+
+// IsRetracted returns if the user retracted their vote.
+func (answer PollAnswer) IsRetracted() bool {
+	return len(answer.OptionIDs) == 0
+}
+
+// Poll contains information about a poll.
+type Poll struct {
+	ID                    string          `json:"id"`
+	Question              string          `json:"question"`
+	Options               []PollOption    `json:"options"`
+	TotalVoterCount       int             `json:"total_voter_count"`
+	IsClosed              bool            `json:"is_closed"`
+	IsAnonymous           bool            `json:"is_anonymous"`
+	Type                  string          `json:"type"`
+	AllowsMultipleAnswers bool            `json:"allows_multiple_answers"`
+	CorrectOptionID       *int            `json:"correct_option_id,omitempty"`    // optional
+	Explanation           string          `json:"explanation,omitempty"`          // optional
+	ExplanationEntities   []MessageEntity `json:"explanation_entities,omitempty"` // optional
+	OpenPeriod            int             `json:"open_period,omitempty"`          // optional
+	CloseDate             int             `json:"close_date,omitempty"`           // optional
+}
+
+// IsQuiz returns if the poll is a quiz.
+func (p Poll) IsQuiz() bool {
+	return p.Type == PollTypeQuiz
+}
+
+// Dice represents an animated emoji that displays a random value.
+type Dice struct {
+	Emoji string `json:"emoji"`
+	Value int    `json:"value"`
+}
+
+// ForumTopic represents a topic of a forum supergroup.
+type ForumTopic struct {
+	MessageThreadID   int    `json:"message_thread_id"`
+	Name              string `json:"name"`
+	IconColor         int    `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"` // optional
+}
+
+// ForumTopicCreated is a service message about a new forum topic.
+type ForumTopicCreated struct {
+	Name              string `json:"name"`
+	IconColor         int    `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"` // optional
+}
+
+// ForumTopicEdited is a service message about an edited forum topic.
+type ForumTopicEdited struct {
+	Name              string  `json:"name,omitempty"`                 // optional
+	IconCustomEmojiID *string `json:"icon_custom_emoji_id,omitempty"` // optional
+}
+
+// BotCommand represents a bot command shown in the command menu.
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// BotCommandScope is the group of users a command list applies to.
+type BotCommandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+	UserID int    `json:"user_id,omitempty"`
+}
+
 // UserProfilePhotos contains a set of user profile photos.
 type UserProfilePhotos struct {
 	TotalCount int           `json:"total_count"`
@@ -358,11 +583,32 @@ type File struct {
 
 // Link returns a full path to the download URL for a File.
 //
-// It requires the Bot Token to create the link.
+// It requires the Bot Token to create the link. Files of a Bot API server
+// running in local mode are returned as their absolute path. The link
+// always uses FileEndpoint: for a bot with its own file endpoint or server
+// URL, use BotAPI.GetFileDirectURL instead.
 func (f *File) Link(token string) string {
+	if f.IsLocal() {
+		return f.FilePath
+	}
+
 	return fmt.Sprintf(FileEndpoint, token, f.FilePath)
 }
 
+// IsLocal returns if the file is an absolute path on the file system of
+// a Bot API server running in local mode. Windows paths are recognized
+// whatever the system of the bot.
+func (f *File) IsLocal() bool {
+	p := f.FilePath
+	if path.IsAbs(p) || strings.HasPrefix(p, `\\`) {
+		return true
+	}
+
+	// A drive letter followed by a separator, such as C:\.
+	return len(p) >= 3 && p[1] == ':' && (p[2] == '\\' || p[2] == '/') &&
+		('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z')
+}
+
 // ReplyKeyboardMarkup allows the Bot to set a custom keyboard.
 type ReplyKeyboardMarkup struct {
 	Keyboard        [][]KeyboardButton `json:"keyboard"`
@@ -449,6 +695,29 @@ type ChatMember struct {
 	CanSendMediaMessages  bool   `json:"can_send_media_messages,omitempty"`   // optional
 	CanSendOtherMessages  bool   `json:"can_send_other_messages,omitempty"`   // optional
 	CanAddWebPagePreviews bool   `json:"can_add_web_page_previews,omitempty"` // optional
+	// IsChatMember is the is_member field of restricted members, telling
+	// if they are in the chat. It is named so as not to clash with the
+	// IsMember method.
+	IsChatMember bool `json:"is_member,omitempty"` // optional
+}
+
+// ChatPermissions describes the actions members of a chat are allowed to
+// do.
+type ChatPermissions struct {
+	CanSendMessages       bool `json:"can_send_messages"`
+	CanSendAudios         bool `json:"can_send_audios"`
+	CanSendDocuments      bool `json:"can_send_documents"`
+	CanSendPhotos         bool `json:"can_send_photos"`
+	CanSendVideos         bool `json:"can_send_videos"`
+	CanSendVideoNotes     bool `json:"can_send_video_notes"`
+	CanSendVoiceNotes     bool `json:"can_send_voice_notes"`
+	CanSendPolls          bool `json:"can_send_polls"`
+	CanSendOtherMessages  bool `json:"can_send_other_messages"`
+	CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
+	CanChangeInfo         bool `json:"can_change_info"`
+	CanInviteUsers        bool `json:"can_invite_users"`
+	CanPinMessages        bool `json:"can_pin_messages"`
+	CanManageTopics       bool `json:"can_manage_topics"`
 }
 
 // IsCreator returns if the ChatMember was the creator of the chat.
@@ -466,6 +735,57 @@ func (chat ChatMember) HasLeft() bool { return chat.Status == "left" }
 // WasKicked returns if the ChatMember was kicked from the chat.
 func (chat ChatMember) WasKicked() bool { return chat.Status == "kicked" }
 
+// ChatMemberUpdated represents changes in the status of a chat member.
+type ChatMemberUpdated struct {
+	Chat          Chat            `json:"chat"`
+	From          User            `json:"from"`
+	Date          int             `json:"date"`
+	OldChatMember ChatMember      `json:"old_chat_member"`
+	NewChatMember ChatMember      `json:"new_chat_member"`
+	InviteLink    *ChatInviteLink `json:"invite_link,omitempty"` // optional
+}
+
+// Joined returns if the user became a member of the chat.
+func (u ChatMemberUpdated) Joined() bool {
+	return !isPresent(u.OldChatMember) && isPresent(u.NewChatMember)
+}
+
+// Left returns if the user stopped being a member of the chat, because
+// they left or were kicked.
+func (u ChatMemberUpdated) Left() bool {
+	return isPresent(u.OldChatMember) && !isPresent(u.NewChatMember)
+}
+
+// isPresent returns if member is in the chat, whatever their rights.
+// Restricted users are only in the chat if they are members.
+func isPresent(member ChatMember) bool {
+	return member.IsCreator() || member.IsAdministrator() || member.IsMember() ||
+		(member.Status == "restricted" && member.IsChatMember)
+}
+
+// ChatJoinRequest represents a request to join a chat.
+type ChatJoinRequest struct {
+	Chat       Chat            `json:"chat"`
+	From       User            `json:"from"`
+	UserChatID int64           `json:"user_chat_id"`
+	Date       int             `json:"date"`
+	Bio        string          `json:"bio,omitempty"`         // optional
+	InviteLink *ChatInviteLink `json:"invite_link,omitempty"` // optional
+}
+
+// ChatInviteLink represents an invite link for a chat.
+type ChatInviteLink struct {
+	InviteLink              string `json:"invite_link"`
+	Creator                 *User  `json:"creator"`
+	CreatesJoinRequest      bool   `json:"creates_join_request"`
+	IsPrimary               bool   `json:"is_primary"`
+	IsRevoked               bool   `json:"is_revoked"`
+	Name                    string `json:"name,omitempty"`                       // optional
+	ExpireDate              int    `json:"expire_date,omitempty"`                // optional
+	MemberLimit             int    `json:"member_limit,omitempty"`               // optional
+	PendingJoinRequestCount int    `json:"pending_join_request_count,omitempty"` // optional
+}
+
 // Game is a game within Telegram.
 type Game struct {
 	Title        string          `json:"title"`
@@ -651,6 +971,86 @@ type InlineQueryResultLocation struct {
 	ThumbHeight         int                   `json:"thumb_height"`
 }
 
+// InlineQueryResultCachedPhoto is an inline query response photo stored
+// on the Telegram servers.
+type InlineQueryResultCachedPhoto struct {
+	Type                string                `json:"type"`          // required
+	ID                  string                `json:"id"`            // required
+	FileID              string                `json:"photo_file_id"` // required
+	Title               string                `json:"title,omitempty"`
+	Description         string                `json:"description,omitempty"`
+	Caption             string                `json:"caption,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+}
+
+// InlineQueryResultCachedSticker is an inline query response sticker
+// stored on the Telegram servers.
+type InlineQueryResultCachedSticker struct {
+	Type                string                `json:"type"`            // required
+	ID                  string                `json:"id"`              // required
+	FileID              string                `json:"sticker_file_id"` // required
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+}
+
+// InlineQueryResultCachedDocument is an inline query response document
+// stored on the Telegram servers.
+type InlineQueryResultCachedDocument struct {
+	Type                string                `json:"type"`             // required
+	ID                  string                `json:"id"`               // required
+	Title               string                `json:"title"`            // required
+	FileID              string                `json:"document_file_id"` // required
+	Description         string                `json:"description,omitempty"`
+	Caption             string                `json:"caption,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+}
+
+// InlineQueryResultContact is an inline query response contact.
+type InlineQueryResultContact struct {
+	Type                string                `json:"type"`         // required
+	ID                  string                `json:"id"`           // required
+	PhoneNumber         string                `json:"phone_number"` // required
+	FirstName           string                `json:"first_name"`   // required
+	LastName            string                `json:"last_name,omitempty"`
+	VCard               string                `json:"vcard,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+	ThumbURL            string                `json:"thumb_url,omitempty"`
+	ThumbWidth          int                   `json:"thumb_width,omitempty"`
+	ThumbHeight         int                   `json:"thumb_height,omitempty"`
+}
+
+// InlineQueryResultVenue is an inline query response venue.
+type InlineQueryResultVenue struct {
+	Type                string                `json:"type"`      // required
+	ID                  string                `json:"id"`        // required
+	Latitude            float64               `json:"latitude"`  // required
+	Longitude           float64               `json:"longitude"` // required
+	Title               string                `json:"title"`     // required
+	Address             string                `json:"address"`   // required
+	FoursquareID        string                `json:"foursquare_id,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+	ThumbURL            string                `json:"thumb_url,omitempty"`
+	ThumbWidth          int                   `json:"thumb_width,omitempty"`
+	ThumbHeight         int                   `json:"thumb_height,omitempty"`
+}
+
+// InlineQueryResultsButton is a button shown above inline query results,
+// opening a private chat with the bot or a Web App.
+type InlineQueryResultsButton struct {
+	Text           string      `json:"text"`
+	WebApp         *WebAppInfo `json:"web_app,omitempty"`
+	StartParameter string      `json:"start_parameter,omitempty"`
+}
+
+// WebAppInfo describes a Web App.
+type WebAppInfo struct {
+	URL string `json:"url"`
+}
+
 // InlineQueryResultGame is an inline query response game.
 type InlineQueryResultGame struct {
 	Type          string                `json:"type"`
@@ -701,6 +1101,56 @@ type InputContactMessageContent struct {
 	LastName    string `json:"last_name"`
 }
 
+// inputMedia is implemented by every InputMedia type.
+type inputMedia interface {
+	mediaFile() interface{}
+}
+
+// BaseInputMedia is the base type of media sent in a media group.
+//
+// Media is the file ID or HTTP URL of a file already known to Telegram.
+// File is a local file to upload instead, a string to a file path,
+// FileBytes or FileReader.
+type BaseInputMedia struct {
+	Type      string      `json:"type"`
+	Media     string      `json:"media"`
+	File      interface{} `json:"-"`
+	Caption   string      `json:"caption,omitempty"`
+	ParseMode string      `json:"parse_mode,omitempty"`
+}
+
+// mediaFile returns the local file to upload, if any.
+func (media BaseInputMedia) mediaFile() interface{} {
+	return media.File
+}
+
+// InputMediaPhoto is a photo to send in a media group.
+type InputMediaPhoto struct {
+	BaseInputMedia
+}
+
+// InputMediaVideo is a video to send in a media group.
+type InputMediaVideo struct {
+	BaseInputMedia
+	Width             int  `json:"width,omitempty"`
+	Height            int  `json:"height,omitempty"`
+	Duration          int  `json:"duration,omitempty"`
+	SupportsStreaming bool `json:"supports_streaming,omitempty"`
+}
+
+// InputMediaAudio is an audio file to send in a media group.
+type InputMediaAudio struct {
+	BaseInputMedia
+	Duration  int    `json:"duration,omitempty"`
+	Performer string `json:"performer,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// InputMediaDocument is a general file to send in a media group.
+type InputMediaDocument struct {
+	BaseInputMedia
+}
+
 // Invoice contains basic information about an invoice.
 type Invoice struct {
 	Title          string `json:"title"`
@@ -752,6 +1202,15 @@ type SuccessfulPayment struct {
 	ProviderPaymentChargeID string     `json:"provider_payment_charge_id"`
 }
 
+// RefundedPayment contains basic information about a refunded payment.
+type RefundedPayment struct {
+	Currency                string `json:"currency"`
+	TotalAmount             int    `json:"total_amount"`
+	InvoicePayload          string `json:"invoice_payload"`
+	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
+	ProviderPaymentChargeID string `json:"provider_payment_charge_id,omitempty"`
+}
+
 // ShippingQuery contains information about an incoming shipping query.
 type ShippingQuery struct {
 	ID              string           `json:"id"`
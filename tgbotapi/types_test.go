@@ -0,0 +1,51 @@
+package tgbotapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileIsLocal(t *testing.T) {
+	for _, p := range []string{"/var/lib/telegram-bot-api/doc.pdf", `C:\bots\doc.pdf`, "d:/bots/doc.pdf", `\\server\share\doc.pdf`} {
+		file := File{FilePath: p}
+		assert.True(t, file.IsLocal(), p)
+		assert.Equal(t, p, file.Link("token"))
+	}
+
+	for _, p := range []string{"documents/file_1.pdf", "C:doc.pdf", ""} {
+		file := File{FilePath: p}
+		assert.False(t, file.IsLocal(), p)
+	}
+	file := File{FilePath: "documents/file_1.pdf"}
+	assert.Equal(t, "https://api.telegram.org/file/bottoken/documents/file_1.pdf", file.Link("token"))
+}
+
+func TestChatMemberUpdated(t *testing.T) {
+	joined := ChatMemberUpdated{
+		OldChatMember: ChatMember{Status: "left"},
+		NewChatMember: ChatMember{Status: "member"},
+	}
+	assert.True(t, joined.Joined())
+	assert.False(t, joined.Left())
+
+	kicked := ChatMemberUpdated{
+		OldChatMember: ChatMember{Status: "restricted", IsChatMember: true},
+		NewChatMember: ChatMember{Status: "kicked"},
+	}
+	assert.False(t, kicked.Joined())
+	assert.True(t, kicked.Left())
+
+	var left ChatMemberUpdated
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"old_chat_member": {"status": "restricted", "is_member": true},
+		"new_chat_member": {"status": "restricted", "is_member": false}
+	}`), &left))
+	assert.False(t, left.Joined())
+	assert.True(t, left.Left())
+
+	rejoined := ChatMemberUpdated{OldChatMember: left.NewChatMember, NewChatMember: left.OldChatMember}
+	assert.True(t, rejoined.Joined())
+	assert.False(t, rejoined.Left())
+}
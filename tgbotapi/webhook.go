@@ -0,0 +1,156 @@
+package tgbotapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WebhookSecretHeader is the header Telegram sends the webhook secret
+// token in.
+const WebhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Default limits of a WebhookServer.
+const (
+	// DefaultWebhookMaxConnections matches the default max_connections
+	// of setWebhook.
+	DefaultWebhookMaxConnections = 40
+	// DefaultWebhookMaxBodySize is far above the size of any update.
+	DefaultWebhookMaxBodySize = 1 << 20
+	// DefaultWebhookEnqueueTimeout is how long a request waits for room in
+	// the updates channel before Telegram is asked to deliver it later.
+	DefaultWebhookEnqueueTimeout = 5 * time.Second
+)
+
+// WebhookServer is a http.Handler receiving the updates Telegram sends to
+// a webhook.
+//
+// Requests are rejected with 401 if the secret token does not match, 400 if
+// the body is not an update, and 503 if too many requests are in flight
+// or the updates channel stays full, so that Telegram delivers the update
+// again later.
+type WebhookServer struct {
+	// SecretToken must match the X-Telegram-Bot-Api-Secret-Token header,
+	// no check is done if it is empty.
+	SecretToken string
+	// MaxBodySize is the largest request body accepted, in bytes.
+	MaxBodySize int64
+	// EnqueueTimeout is how long a request waits for room in the updates
+	// channel.
+	EnqueueTimeout time.Duration
+
+	ch  chan Update
+	sem chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookServer creates a WebhookServer accepting the updates Telegram
+// sends for config, so the same config can be passed to SetWebhook.
+//
+// The number of requests handled at once is limited to MaxConnections,
+// or DefaultWebhookMaxConnections if it is not set.
+func NewWebhookServer(bot *BotAPI, config WebhookConfig) *WebhookServer {
+	connections := config.MaxConnections
+	if connections <= 0 {
+		connections = DefaultWebhookMaxConnections
+	}
+
+	return &WebhookServer{
+		SecretToken:    config.SecretToken,
+		MaxBodySize:    DefaultWebhookMaxBodySize,
+		EnqueueTimeout: DefaultWebhookEnqueueTimeout,
+		ch:             make(chan Update, bot.Buffer),
+		sem:            make(chan struct{}, connections),
+	}
+}
+
+// Updates returns the channel received updates are delivered on.
+func (s *WebhookServer) Updates() UpdatesChannel {
+	return s.ch
+}
+
+// Close stops accepting updates and closes the updates channel, once
+// requests waiting for room in the channel have given up.
+func (s *WebhookServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// ServeHTTP handles a request sent by Telegram to the webhook.
+func (s *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.SecretToken != "" {
+		token := r.Header.Get(WebhookSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.SecretToken)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	default:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	var update Update
+	if r.Body == nil {
+		http.Error(w, "empty body", http.StatusBadRequest)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, s.MaxBodySize)
+	if err := json.NewDecoder(body).Decode(&update); err != nil {
+		log.Error("webhook update decode failed", zap.String("remote", r.RemoteAddr), zap.Error(err))
+		http.Error(w, "bad update", http.StatusBadRequest)
+		return
+	}
+
+	if !s.enqueue(r, update) {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// enqueue delivers the update on the updates channel, or returns false if
+// there was no room before the timeout.
+func (s *WebhookServer) enqueue(r *http.Request, update Update) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false
+	}
+
+	timer := time.NewTimer(s.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.ch <- update:
+		return true
+	case <-timer.C:
+		log.Info("webhook updates channel full", zap.Int("update_id", update.UpdateID))
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,37 @@
+package tgbotapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookServer(t *testing.T) {
+	server := NewWebhookServer(&BotAPI{Buffer: 1}, WebhookConfig{SecretToken: "s3cret"})
+	server.EnqueueTimeout = 10 * time.Millisecond
+
+	post := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(WebhookSecretHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", `{"update_id":1}`))
+	assert.Equal(t, http.StatusBadRequest, post("s3cret", `{"update_id":`))
+	assert.Equal(t, http.StatusOK, post("s3cret", `{"update_id":2}`))
+	assert.Equal(t, http.StatusServiceUnavailable, post("s3cret", `{"update_id":3}`))
+
+	update := <-server.Updates()
+	assert.Equal(t, 2, update.UpdateID)
+
+	server.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, post("s3cret", `{"update_id":4}`))
+}